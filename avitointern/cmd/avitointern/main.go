@@ -1,26 +1,40 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
+	"avitointern/pkg/api"
 	"avitointern/pkg/database"
 	"avitointern/pkg/handlers"
-	"avitointern/pkg/middleware"
+	"avitointern/pkg/i18n"
+	"avitointern/pkg/mail"
+	"avitointern/pkg/migrations"
+	"avitointern/pkg/openapi"
+	"avitointern/pkg/server"
 	"avitointern/pkg/session"
 	"avitointern/pkg/tenders"
+	"avitointern/pkg/token"
 	"avitointern/pkg/user"
 
-	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 )
 
 func main() {
+	migrate := flag.Bool("migrate", false, "apply pending database migrations at startup")
+	debug := flag.Bool("debug", false, "also validate responses against the OpenAPI spec")
+	exposeResetTokens := flag.Bool("dev-reset-tokens", false, "return password reset tokens in the response (development only)")
+	flag.Parse()
 
-	templates := template.Must(template.ParseGlob("./static/html/*"))
+	templates := template.Must(template.New("").Funcs(i18n.TemplateFuncs()).ParseGlob("./static/html/*"))
 
-	sm := session.NewSessionsManager()
 	zapLogger, err := zap.NewProduction()
 	if err != nil {
 		log.Println("err with zapLogger")
@@ -33,51 +47,139 @@ func main() {
 	}()
 	logger := zapLogger.Sugar()
 
-	userRepo := user.NewMemoryRepo()
-	tendersRepo := tenders.NewMemoryRepo()
-	sqlManager := database.NewMemoryRepo()
-	sqlManager.Init()
+	storage, err := database.Storage()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	userHandler := &handlers.UserHandler{
-		Tmpl:     templates,
-		UserRepo: userRepo,
-		Logger:   logger,
-		Sessions: sm,
+	// The storage shares the state machine with the API: it closes tenders
+	// itself once a bid is approved.
+	var states *tenders.StateMachine
+	var db database.Database
+	var userRepo user.UserRepo
+	var sessionStore session.Store
+	switch storage {
+	case database.StorageMemory:
+		userRepo = user.NewMemoryRepo()
+		states = handlers.NewTenderStates(userRepo, logger)
+		memoryDB := database.NewMemoryDB(userRepo)
+		memoryDB.States = states
+		db = memoryDB
+		sessionStore = session.NewMemoryStore()
+		logger.Infof("using in-memory storage, data is lost on restart")
+		if *migrate {
+			logger.Infof("-migrate is ignored with in-memory storage")
+		}
+	default:
+		sqlManager := database.NewMemoryRepo()
+		if err = sqlManager.Init(context.Background()); err != nil {
+			log.Fatalf("Unable to connect to database: %v", err)
+		}
+		if *migrate {
+			applyMigrations(sqlManager, logger)
+		}
+		userRepo = user.NewPgRepo(sqlManager.Pool)
+		states = handlers.NewTenderStates(userRepo, logger)
+		sqlManager.States = states
+		db = sqlManager
+		sessionStore = session.NewPgStore(sqlManager.Pool)
 	}
 
-	handlers := &handlers.TendersHandler{
-		SQL:         sqlManager,
-		Tmpl:        templates,
-		Logger:      logger,
-		TendersRepo: tendersRepo,
+	sm := session.NewSessionsManager(sessionStore, userRepo)
+	if sm.IdleTTL, err = envDuration("SESSION_IDLE_TTL", sm.IdleTTL); err != nil {
+		log.Fatal(err)
 	}
+	if sm.AbsoluteTTL, err = envDuration("SESSION_ABSOLUTE_TTL", sm.AbsoluteTTL); err != nil {
+		log.Fatal(err)
+	}
+	go cleanupSessions(sm, logger)
 
-	r := mux.NewRouter()
-	r.HandleFunc("/", userHandler.Index).Methods("GET")
-	r.HandleFunc("/ping", userHandler.Ping).Methods("GET")
-	r.HandleFunc("/login", userHandler.Login).Methods("POST")
-	r.HandleFunc("/logout", userHandler.Logout).Methods("POST")
+	keys, persistent, err := token.KeysetFromEnv()
+	if err != nil {
+		log.Fatalf("Bad token keys: %v", err)
+	}
+	if !persistent {
+		logger.Warnf("JWT_KEYS is not set, bearer tokens are signed with a random key and expire on restart")
+	}
+	tokens := token.NewManager(keys, sessionStore)
+	var spec *openapi.Spec
+	if specPath := os.Getenv("OPENAPI_SPEC"); specPath != "" {
+		spec, err = openapi.Load(specPath, api.V1Prefix, api.Prefix)
+	} else {
+		spec, err = openapi.LoadEmbedded(api.V1Prefix, api.Prefix)
+	}
+	if err != nil {
+		log.Fatalf("Unable to load OpenAPI spec: %v", err)
+	}
 
-	r.HandleFunc("/tenders", handlers.Tenders).Methods("GET")
-	r.HandleFunc("/tenders/new", handlers.New).Methods("POST")
-	r.HandleFunc("/tenders/my", handlers.My).Methods("GET")
-	r.HandleFunc("/tenders/{tenderID}/status", handlers.GetStatus).Methods("GET")
-	r.HandleFunc("/tenders/{tenderID}/status", handlers.EditStatus).Methods("PUT")
-	r.HandleFunc("/tenders/{tenderID}/edit", handlers.Edit).Methods("PATCH")
-	r.HandleFunc("/tenders/{tenderID}/rollback/{version}", handlers.Rollback).Methods("PUT")
+	handler := server.Handler(server.Config{
+		DB:       db,
+		Users:    userRepo,
+		Sessions: sm,
+		Tokens:   tokens,
+		Mailer:   mail.NewStub(logger),
+		States:   states,
+		Spec:     spec,
+		Debug:    *debug,
+		Tmpl:     templates,
+		Logger:   logger,
 
-	mux := middleware.Auth(sm, r)
-	mux = middleware.AccessLog(logger, mux)
-	mux = middleware.Panic(mux)
+		ExposeResetTokens: *exposeResetTokens,
+	})
+	if *exposeResetTokens {
+		logger.Warnf("-dev-reset-tokens is set, password reset tokens are returned to anyone who asks")
+	}
 
 	addr := ":8080"
 	logger.Infow("starting server",
 		"type", "START",
 		"addr", addr,
 	)
-	err = http.ListenAndServe(addr, mux)
+	err = http.ListenAndServe(addr, handler)
 	if err != nil {
 		log.Println("err with ListenAndServe")
 	}
-	handlers.SQL.Close()
+	db.Close()
+}
+
+func applyMigrations(sqlManager *database.SQLManager, logger *zap.SugaredLogger) {
+	db := stdlib.OpenDBFromPool(sqlManager.Pool)
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Println("Error closing database connection:", err)
+		}
+	}()
+
+	done, err := migrations.New(db).Up(context.Background())
+	if err != nil {
+		log.Fatalf("Unable to apply migrations: %v", err)
+	}
+	logger.Infof("applied %d migrations", len(done))
+}
+
+const sessionCleanupInterval = 10 * time.Minute
+
+func cleanupSessions(sm *session.SessionsManager, logger *zap.SugaredLogger) {
+	for range time.Tick(sessionCleanupInterval) {
+		deleted, err := sm.Cleanup(context.Background())
+		if err != nil {
+			logger.Errorf("session cleanup: %v", err)
+			continue
+		}
+		if deleted > 0 {
+			logger.Infof("removed %d expired sessions", deleted)
+		}
+	}
+}
+
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"avitointern/pkg/database"
+	"avitointern/pkg/migrations"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [-n N] revert the last N applied migrations (default 1)
+  status      list migrations and whether they are applied
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	cfg, err := database.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Bad database config: %v", err)
+	}
+	pool, err := database.Open(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	db := stdlib.OpenDBFromPool(pool)
+	defer func() {
+		if err = db.Close(); err != nil {
+			log.Println("Error closing database connection:", err)
+		}
+	}()
+
+	migrator := migrations.New(db)
+
+	switch os.Args[1] {
+	case "up":
+		done, err := migrator.Up(ctx)
+		for _, m := range done {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate up: %v", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("nothing to apply")
+		}
+	case "down":
+		fs := flag.NewFlagSet("down", flag.ExitOnError)
+		steps := fs.Int("n", 1, "number of migrations to revert")
+		if err = fs.Parse(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		done, err := migrator.Down(ctx, *steps)
+		for _, m := range done {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("migrate down: %v", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("migrate status: %v", err)
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-20s %s\n", st.Version, st.Name, state)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"avitointern/pkg/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// Prefix is an alias for the current version, as the assignment spec
+	// addresses the API.
+	Prefix   = "/api"
+	V1Prefix = "/api/v1"
+)
+
+type Handlers struct {
+	Users         *handlers.UserHandler
+	Health        *handlers.HealthHandler
+	Tenders       *handlers.TendersHandler
+	Bids          *handlers.BidsHandler
+	Organizations *handlers.OrganizationsHandler
+}
+
+// Mount adds the JSON API to r under /api/v1 and under /api. Public routes
+// (ping, login, registration, tokens) get only the common middleware, the
+// rest runs behind auth as well. Middleware is applied in the given order,
+// the first being the outermost.
+func Mount(r *mux.Router, h *Handlers, auth mux.MiddlewareFunc, common ...mux.MiddlewareFunc) {
+	// v1 goes first: the /api prefix matches /api/v1 paths as well.
+	for _, prefix := range []string{V1Prefix, Prefix} {
+		versioned := r.PathPrefix(prefix).Subrouter()
+
+		public := versioned.NewRoute().Subrouter()
+		public.Use(common...)
+		mountPublicV1(public, h)
+
+		private := versioned.NewRoute().Subrouter()
+		private.Use(auth)
+		private.Use(common...)
+		mountV1(private, h)
+	}
+}
+
+func mountPublicV1(r *mux.Router, h *Handlers) {
+	r.HandleFunc("/ping", h.Users.Ping).Methods("GET")
+	r.HandleFunc("/health", h.Health.Health).Methods("GET")
+	r.HandleFunc("/login", h.Users.Login).Methods("POST")
+	r.HandleFunc("/register", h.Users.Register).Methods("POST")
+	r.HandleFunc("/token", h.Users.Token).Methods("POST")
+	r.HandleFunc("/token/refresh", h.Users.RefreshToken).Methods("POST")
+	r.HandleFunc("/password/reset/request", h.Users.RequestPasswordReset).Methods("POST")
+	r.HandleFunc("/password/reset", h.Users.ResetPassword).Methods("POST")
+}
+
+func mountV1(r *mux.Router, h *Handlers) {
+	r.HandleFunc("/logout", h.Users.Logout).Methods("POST")
+	r.HandleFunc("/sessions", h.Users.ListSessions).Methods("GET")
+	r.HandleFunc("/sessions", h.Users.RevokeSessions).Methods("DELETE")
+	r.HandleFunc("/sessions/{sessionID}", h.Users.RevokeSession).Methods("DELETE")
+	r.HandleFunc("/password/change", h.Users.ChangePassword).Methods("POST")
+	r.HandleFunc("/settings/language", h.Users.SetLanguage).Methods("PUT")
+
+	r.HandleFunc("/tenders", h.Tenders.Tenders).Methods("GET")
+	r.HandleFunc("/tenders/new", h.Tenders.New).Methods("POST")
+	r.HandleFunc("/tenders/my", h.Tenders.My).Methods("GET")
+	r.HandleFunc("/tenders/{tenderID}", h.Tenders.Get).Methods("GET")
+	r.HandleFunc("/tenders/{tenderID}/status", h.Tenders.GetStatus).Methods("GET")
+	r.HandleFunc("/tenders/{tenderID}/status", h.Tenders.EditStatus).Methods("PUT")
+	r.HandleFunc("/tenders/{tenderID}/edit", h.Tenders.Edit).Methods("PATCH")
+	r.HandleFunc("/tenders/{tenderID}/rollback/{version}", h.Tenders.Rollback).Methods("PUT")
+	r.HandleFunc("/tenders/{tenderID}/versions", h.Tenders.Versions).Methods("GET")
+	r.HandleFunc("/tenders/{tenderID}/versions/diff", h.Tenders.VersionsDiff).Methods("GET")
+	r.HandleFunc("/tenders/{tenderID}/versions/{version:[0-9]+}", h.Tenders.Version).Methods("GET")
+
+	r.HandleFunc("/organizations", h.Organizations.New).Methods("POST")
+	r.HandleFunc("/organizations/{organizationID}", h.Organizations.Profile).Methods("GET")
+	r.HandleFunc("/organizations/{organizationID}", h.Organizations.Edit).Methods("PATCH")
+	r.HandleFunc("/organizations/{organizationID}/tenders", h.Organizations.Tenders).Methods("GET")
+	r.HandleFunc("/organizations/{organizationID}/responsibles/{username}", h.Organizations.AddResponsible).Methods("PUT")
+	r.HandleFunc("/organizations/{organizationID}/responsibles/{username}", h.Organizations.RemoveResponsible).Methods("DELETE")
+
+	r.HandleFunc("/bids/new", h.Bids.New).Methods("POST")
+	r.HandleFunc("/bids/my", h.Bids.My).Methods("GET")
+	r.HandleFunc("/bids/{tenderID}/list", h.Bids.List).Methods("GET")
+	r.HandleFunc("/bids/{bidID}/status", h.Bids.GetStatus).Methods("GET")
+	r.HandleFunc("/bids/{bidID}/status", h.Bids.EditStatus).Methods("PUT")
+	r.HandleFunc("/bids/{bidID}/edit", h.Bids.Edit).Methods("PATCH")
+	r.HandleFunc("/bids/{bidID}/submit_decision", h.Bids.SubmitDecision).Methods("PUT")
+	r.HandleFunc("/bids/{bidID}/feedback", h.Bids.Feedback).Methods("PUT")
+	r.HandleFunc("/bids/{tenderID}/reviews", h.Bids.Reviews).Methods("GET")
+}
@@ -0,0 +1,130 @@
+// Package v1 holds the response bodies of /api/v1. They follow the
+// assignment spec and are built from the domain types by hand, so renaming a
+// field of tenders.Tender or bids.Bid does not change what clients receive.
+package v1
+
+import (
+	"avitointern/pkg/bids"
+	"avitointern/pkg/tenders"
+)
+
+type Tender struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	ServiceType    string `json:"serviceType"`
+	Status         string `json:"status"`
+	OrganizationID string `json:"organizationId"`
+	Version        int32  `json:"version"`
+	CreatedAt      string `json:"createdAt"` // RFC3339 format.
+}
+
+func NewTender(t *tenders.Tender) Tender {
+	return Tender{
+		ID:             t.TenderID,
+		Name:           t.TenderName,
+		Description:    t.TenderDescription,
+		ServiceType:    string(t.ServiceType),
+		Status:         string(t.Status),
+		OrganizationID: t.OrganizationID,
+		Version:        t.Version,
+		CreatedAt:      t.CreatedAt,
+	}
+}
+
+// NewTenders never returns nil, an empty list is encoded as [].
+func NewTenders(list []*tenders.Tender) []Tender {
+	out := make([]Tender, 0, len(list))
+	for _, t := range list {
+		out = append(out, NewTender(t))
+	}
+	return out
+}
+
+type TenderVersion struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ServiceType string `json:"serviceType"`
+	Version     int32  `json:"version"`
+	Status      string `json:"status"`
+	ModifiedBy  string `json:"modifiedBy"`
+	ModifiedAt  string `json:"modifiedAt"` // RFC3339 format.
+	RollbackOf  int32  `json:"rollbackOf,omitempty"`
+}
+
+func NewTenderVersion(v *tenders.TenderVer) TenderVersion {
+	return TenderVersion{
+		Name:        v.TenderName,
+		Description: v.TenderDescription,
+		ServiceType: v.ServiceType,
+		Version:     v.Version,
+		Status:      string(v.Status),
+		ModifiedBy:  v.ModifiedBy,
+		ModifiedAt:  v.ModifiedAt,
+		RollbackOf:  v.RollbackOf,
+	}
+}
+
+func NewTenderVersions(list []*tenders.TenderVer) []TenderVersion {
+	out := make([]TenderVersion, 0, len(list))
+	for _, v := range list {
+		out = append(out, NewTenderVersion(v))
+	}
+	return out
+}
+
+type Bid struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	TenderID    string `json:"tenderId"`
+	AuthorType  string `json:"authorType"`
+	AuthorID    string `json:"authorId"`
+	Version     int32  `json:"version"`
+	CreatedAt   string `json:"createdAt"` // RFC3339 format.
+	// Decision is Approved or Rejected once the bid is decided. The spec
+	// has no such field, its bid schema allows more properties.
+	Decision string `json:"decision,omitempty"`
+}
+
+func NewBid(b *bids.Bid) Bid {
+	return Bid{
+		ID:          b.BidID,
+		Name:        b.BidName,
+		Description: b.BidDescription,
+		Status:      string(b.Status),
+		TenderID:    b.TenderID,
+		AuthorType:  string(b.AuthorType),
+		AuthorID:    b.AuthorID,
+		Version:     b.Version,
+		CreatedAt:   b.CreatedAt,
+		Decision:    string(b.Decision),
+	}
+}
+
+func NewBids(list []*bids.Bid) []Bid {
+	out := make([]Bid, 0, len(list))
+	for _, b := range list {
+		out = append(out, NewBid(b))
+	}
+	return out
+}
+
+type BidReview struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	CreatedAt   string `json:"createdAt"` // RFC3339 format.
+}
+
+func NewBidReviews(list []*bids.Review) []BidReview {
+	out := make([]BidReview, 0, len(list))
+	for _, rv := range list {
+		out = append(out, BidReview{
+			ID:          rv.ReviewID,
+			Description: rv.Description,
+			CreatedAt:   rv.CreatedAt,
+		})
+	}
+	return out
+}
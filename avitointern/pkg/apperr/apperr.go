@@ -0,0 +1,229 @@
+package apperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"avitointern/pkg/i18n"
+
+	"golang.org/x/text/language"
+)
+
+// Code is a stable machine-readable error identifier. Clients may switch on
+// it; the detail text is for people and may change.
+type Code string
+
+const (
+	CodeBadRequest         Code = "bad_request"
+	CodeUnauthorized       Code = "unauthorized"
+	CodeForbidden          Code = "forbidden"
+	CodeNotFound           Code = "not_found"
+	CodeMethodNotAllowed   Code = "method_not_allowed"
+	CodeTenderNotFound     Code = "tender_not_found"
+	CodeBidNotFound        Code = "bid_not_found"
+	CodeOrgNotFound        Code = "organization_not_found"
+	CodeVersionNotFound    Code = "version_not_found"
+	CodeConflict           Code = "conflict"
+	CodeVersionConflict    Code = "version_conflict"
+	CodeIllegalTransition  Code = "illegal_transition"
+	CodePreconditionFailed Code = "precondition_failed"
+	CodeInternal           Code = "internal"
+
+	// Domain errors declared next to the code that returns them.
+	CodeBidDecided      Code = "bid_decided"
+	CodeBidClosed       Code = "bid_closed"
+	CodeBidNotPublished Code = "bid_not_published"
+	CodeAlreadyDecided  Code = "already_decided"
+	CodeTenderNotActive Code = "tender_not_active"
+	CodeDuplicateTender Code = "duplicate_tender"
+	CodeDuplicateINN    Code = "duplicate_inn"
+	CodeDuplicateOGRN   Code = "duplicate_ogrn"
+	CodeUserNotFound    Code = "user_not_found"
+	CodeUserExists      Code = "user_exists"
+	CodeBadCredentials  Code = "bad_credentials"
+	CodeWeakPassword    Code = "weak_password"
+	CodeBadResetToken   Code = "bad_reset_token"
+	CodeNotResponsible  Code = "not_responsible"
+	CodeLastResponsible Code = "last_responsible"
+)
+
+// Error is a domain error that knows how it is presented over HTTP. Errors
+// with the same code match each other in errors.Is, so a copy with another
+// detail still matches the sentinel it came from. Detail is in English; the
+// problem body carries it translated to the request language.
+type Error struct {
+	Code   Code
+	Status int
+	Detail string
+
+	format string
+	args   []interface{}
+}
+
+func New(code Code, status int, detail string) *Error {
+	return &Error{Code: code, Status: status, Detail: detail, format: detail}
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetail returns a copy of e with another detail.
+func (e *Error) WithDetail(detail string) *Error {
+	return &Error{Code: e.Code, Status: e.Status, Detail: detail, format: detail}
+}
+
+// WithDetailf is WithDetail for a detail with arguments. The format is the
+// message key, so the detail is translated with the arguments in place.
+func (e *Error) WithDetailf(format string, args ...interface{}) *Error {
+	return &Error{Code: e.Code, Status: e.Status, Detail: fmt.Sprintf(format, args...), format: format, args: args}
+}
+
+// Message is implemented by errors whose text has arguments, for the problem
+// detail to be translated as a whole.
+type Message interface {
+	error
+	Message() (format string, args []interface{})
+}
+
+// Text is an argument of a message that is a message itself and gets
+// translated too.
+type Text string
+
+var (
+	ErrBadRequest         = New(CodeBadRequest, http.StatusBadRequest, "the request is invalid")
+	ErrUnauthorized       = New(CodeUnauthorized, http.StatusUnauthorized, "user Unauthorized")
+	ErrForbidden          = New(CodeForbidden, http.StatusForbidden, "there are not enough permissions to perform the action")
+	ErrNotFound           = New(CodeNotFound, http.StatusNotFound, "the resource was not found")
+	ErrMethodNotAllowed   = New(CodeMethodNotAllowed, http.StatusMethodNotAllowed, "the method is not allowed")
+	ErrTenderNotFound     = New(CodeTenderNotFound, http.StatusNotFound, "the tender was not found")
+	ErrBidNotFound        = New(CodeBidNotFound, http.StatusNotFound, "the bid was not found")
+	ErrOrgNotFound        = New(CodeOrgNotFound, http.StatusNotFound, "the organization was not found")
+	ErrVersionNotFound    = New(CodeVersionNotFound, http.StatusNotFound, "the version was not found")
+	ErrConflict           = New(CodeConflict, http.StatusConflict, "the request conflicts with the current state")
+	ErrVersionConflict    = New(CodeVersionConflict, http.StatusConflict, "the tender was modified concurrently")
+	ErrIllegalTransition  = New(CodeIllegalTransition, http.StatusConflict, "illegal tender status transition")
+	ErrPreconditionFailed = New(CodePreconditionFailed, http.StatusPreconditionFailed, "the resource does not match If-Match")
+	ErrInternal           = New(CodeInternal, http.StatusInternalServerError, "internal server error")
+)
+
+// FromStatus is the generic error for a status, for handlers that only know
+// what to answer and not which domain error caused it.
+func FromStatus(status int, detail string) *Error {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrBadRequest.WithDetail(detail)
+	case http.StatusUnauthorized:
+		return ErrUnauthorized.WithDetail(detail)
+	case http.StatusForbidden:
+		return ErrForbidden.WithDetail(detail)
+	case http.StatusNotFound:
+		return ErrNotFound.WithDetail(detail)
+	case http.StatusMethodNotAllowed:
+		return ErrMethodNotAllowed.WithDetail(detail)
+	case http.StatusConflict:
+		return ErrConflict.WithDetail(detail)
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed.WithDetail(detail)
+	case http.StatusInternalServerError:
+		return ErrInternal.WithDetail(detail)
+	}
+	return New(Code(http.StatusText(status)), status, detail)
+}
+
+// From finds the domain error in err's chain. The detail is the full message
+// of err, which names the failed step, or the Message of an error in the
+// chain. Unknown errors become ErrInternal and their text is not shown to the
+// client.
+func From(err error) *Error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return ErrInternal
+	}
+	if e.Status >= http.StatusInternalServerError || err == error(e) {
+		return e
+	}
+	var m Message
+	if errors.As(err, &m) {
+		format, args := m.Message()
+		return e.WithDetailf(format, args...)
+	}
+	return e.WithDetail(err.Error())
+}
+
+type ctxKey int
+
+const requestIDKey ctxKey = 1
+
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Problem is an RFC 7807 application/problem+json body. Reason repeats the
+// detail for clients written against the assignment spec, whose error
+// response has only that field.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Code      Code        `json:"code"`
+	Detail    string      `json:"detail"`
+	Reason    string      `json:"reason"`
+	RequestID string      `json:"requestId,omitempty"`
+	Errors    interface{} `json:"errors,omitempty"`
+}
+
+// NewProblem renders e in the language of the request.
+func NewProblem(r *http.Request, e *Error) *Problem {
+	detail := e.Detail
+	if e.format != "" {
+		detail = localize(i18n.LanguageFromContext(r.Context()), e.format, e.args)
+	}
+	return &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Code:      e.Code,
+		Detail:    detail,
+		Reason:    detail,
+		RequestID: RequestIDFromContext(r.Context()),
+	}
+}
+
+func localize(tag language.Tag, format string, args []interface{}) string {
+	translated := make([]interface{}, len(args))
+	for i, arg := range args {
+		if text, ok := arg.(Text); ok {
+			arg = i18n.Sprintf(tag, string(text))
+		}
+		translated[i] = arg
+	}
+	return i18n.Sprintf(tag, format, translated...)
+}
+
+func (p *Problem) Write(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+// Write answers with the problem for err and returns it, so the caller can
+// log server errors.
+func Write(w http.ResponseWriter, r *http.Request, err error) *Problem {
+	p := NewProblem(r, From(err))
+	_ = p.Write(w)
+	return p
+}
@@ -0,0 +1,186 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/user"
+
+	"go.uber.org/zap"
+)
+
+type Role string
+
+const (
+	OrgResponsible Role = "org_responsible"
+	OrgViewer      Role = "org_viewer"
+	PlatformAdmin  Role = "platform_admin"
+	// Owner is held by the user a resource personally belongs to, such as
+	// the author of a bid submitted on the user's own behalf.
+	Owner Role = "owner"
+)
+
+type Permission string
+
+const (
+	TenderCreate  Permission = "tender.create"
+	TenderView    Permission = "tender.view"
+	TenderEdit    Permission = "tender.edit"
+	TenderPublish Permission = "tender.publish"
+	TenderHistory Permission = "tender.history"
+	BidCreate     Permission = "bid.create"
+	BidView       Permission = "bid.view"
+	BidEdit       Permission = "bid.edit"
+	BidDecide     Permission = "bid.decide"
+	BidFeedback   Permission = "bid.feedback"
+	BidReviews    Permission = "bid.reviews"
+	OrgEdit       Permission = "organization.edit"
+	// OrgResponsibles allows assigning and removing organization responsibles.
+	OrgResponsibles Permission = "organization.responsibles"
+)
+
+var rolePermissions = map[Role][]Permission{
+	OrgResponsible: {
+		TenderCreate, TenderView, TenderEdit, TenderPublish, TenderHistory,
+		BidCreate, BidView, BidEdit, BidDecide, BidFeedback, BidReviews,
+		OrgEdit, OrgResponsibles,
+	},
+	OrgViewer: {TenderView, TenderHistory, BidView},
+	Owner:     {BidCreate, BidView, BidEdit},
+}
+
+// Grants reports whether the role carries the permission. A platform admin
+// holds every permission.
+func Grants(role Role, perm Permission) bool {
+	if role == PlatformAdmin {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource is what a permission is checked against: the owning organization
+// and, for personal resources, the owning user.
+type Resource struct {
+	OrganizationID string
+	OwnerID        string
+}
+
+var ErrDenied = apperr.ErrForbidden
+
+// DeniedError carries the reason of a denial; it matches ErrDenied.
+type DeniedError struct {
+	Permission Permission
+	Reason     string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%s denied: %s", e.Permission, e.Reason)
+}
+
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+type Policy struct {
+	Users  user.UserRepo
+	Logger *zap.SugaredLogger
+}
+
+func NewPolicy(users user.UserRepo, logger *zap.SugaredLogger) *Policy {
+	return &Policy{Users: users, Logger: logger}
+}
+
+// Roles returns the roles u holds on the resource.
+func (p *Policy) Roles(ctx context.Context, u *user.User, res Resource) ([]Role, error) {
+	var roles []Role
+	if u.IsAdmin {
+		roles = append(roles, PlatformAdmin)
+	}
+	if res.OwnerID != "" && res.OwnerID == u.ID {
+		roles = append(roles, Owner)
+	}
+	if res.OrganizationID != "" {
+		responsible, err := p.Users.IsResponsible(ctx, u.Username, res.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		if responsible {
+			roles = append(roles, OrgResponsible)
+		}
+		viewer, err := p.Users.IsViewer(ctx, u.Username, res.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		if viewer {
+			roles = append(roles, OrgViewer)
+		}
+	}
+	return roles, nil
+}
+
+// Allowed reports whether u may perform perm on the resource without logging
+// anything; use it for checks that fall back to another resource.
+func (p *Policy) Allowed(ctx context.Context, u *user.User, perm Permission, res Resource) (bool, error) {
+	if u == nil {
+		return false, nil
+	}
+	roles, err := p.Roles(ctx, u, res)
+	if err != nil {
+		return false, err
+	}
+	return grantsAny(roles, perm), nil
+}
+
+// Authorize returns nil when one of the user's roles on the resource grants
+// perm, a *DeniedError otherwise. Denials are logged with their reason.
+func (p *Policy) Authorize(ctx context.Context, u *user.User, perm Permission, res Resource) error {
+	if u == nil {
+		return p.deny(nil, perm, res, "no user")
+	}
+
+	roles, err := p.Roles(ctx, u, res)
+	if err != nil {
+		return err
+	}
+	if grantsAny(roles, perm) {
+		return nil
+	}
+
+	reason := "user has no role on the resource"
+	if len(roles) > 0 {
+		reason = fmt.Sprintf("roles %v do not grant it", roles)
+	} else if res.OrganizationID != "" {
+		reason = fmt.Sprintf("user is not a member of organization %s", res.OrganizationID)
+	}
+	return p.deny(u, perm, res, reason)
+}
+
+func grantsAny(roles []Role, perm Permission) bool {
+	for _, role := range roles {
+		if Grants(role, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) deny(u *user.User, perm Permission, res Resource, reason string) error {
+	username := ""
+	if u != nil {
+		username = u.Username
+	}
+	p.Logger.Infow("access denied",
+		"user", username,
+		"permission", perm,
+		"organization", res.OrganizationID,
+		"owner", res.OwnerID,
+		"reason", reason,
+	)
+	return &DeniedError{Permission: perm, Reason: reason}
+}
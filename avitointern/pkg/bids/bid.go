@@ -0,0 +1,71 @@
+package bids
+
+import "avitointern/pkg/paging"
+
+type Status string
+
+const (
+	Created   Status = "Created"
+	Published Status = "Published"
+	Canceled  Status = "Canceled"
+)
+
+type AuthorType string
+
+const (
+	Organization AuthorType = "Organization"
+	User         AuthorType = "User"
+)
+
+type Bid struct {
+	BidID           string     `json:"id"`
+	BidName         string     `json:"name"`
+	BidDescription  string     `json:"description"`
+	Status          Status     `json:"status"`
+	TenderID        string     `json:"tenderId"`
+	AuthorType      AuthorType `json:"authorType"`
+	AuthorID        string     `json:"authorId"`
+	Version         int32      `json:"version"`   // min 1, def 1
+	CreatedAt       string     `json:"createdAt"` // RFC3339 format.
+	CreatorUsername string     `json:"-"`
+	// Decision is the outcome once the decisions resolve the bid.
+	Decision  Decision          `json:"decision,omitempty"`
+	Versions  map[int32]*BidVer `json:"-"`
+	Decisions []*BidDecision    `json:"-"`
+}
+
+type BidVer struct {
+	BidName        string `json:"name"`
+	BidDescription string `json:"description"`
+	Version        int32  `json:"version"`
+	Status         Status `json:"status"`
+}
+
+func ValidStatus(status string) bool {
+	switch Status(status) {
+	case Created, Published, Canceled:
+		return true
+	}
+	return false
+}
+
+func ValidAuthorType(authorType string) bool {
+	switch AuthorType(authorType) {
+	case Organization, User:
+		return true
+	}
+	return false
+}
+
+// SortName is the only sort order of bid listings.
+const SortName = "name"
+
+// CursorAfter returns the cursor that continues a bid listing after bid.
+func CursorAfter(bid *Bid) *paging.Cursor {
+	return &paging.Cursor{Sort: SortName, Value: bid.BidName, ID: bid.BidID}
+}
+
+// ValidCursor reports whether the cursor was made for a bid listing.
+func ValidCursor(c *paging.Cursor) bool {
+	return c.Sort == SortName && !c.Desc
+}
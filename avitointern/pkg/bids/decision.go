@@ -0,0 +1,48 @@
+package bids
+
+type Decision string
+
+const (
+	Approved Decision = "Approved"
+	Rejected Decision = "Rejected"
+)
+
+const maxQuorum = 3
+
+type BidDecision struct {
+	Username  string   `json:"username"`
+	Decision  Decision `json:"decision"`
+	CreatedAt string   `json:"createdAt"` // RFC3339 format.
+}
+
+func ValidDecision(decision string) bool {
+	switch Decision(decision) {
+	case Approved, Rejected:
+		return true
+	}
+	return false
+}
+
+// Quorum is min(3, number of organization responsibles), but never less than one
+// approval, so an organization without registered responsibles can't auto-approve.
+func Quorum(responsibles int) int {
+	return max(1, min(maxQuorum, responsibles))
+}
+
+// Resolve returns the final decision on a bid or "" while it is still pending:
+// a single reject rejects the bid, approvals count once the quorum is reached.
+func Resolve(decisions []*BidDecision, responsibles int) Decision {
+	approvals := 0
+	for _, d := range decisions {
+		switch d.Decision {
+		case Rejected:
+			return Rejected
+		case Approved:
+			approvals++
+		}
+	}
+	if approvals >= Quorum(responsibles) {
+		return Approved
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package bids_test
+
+import (
+	"testing"
+
+	"avitointern/pkg/bids"
+)
+
+func decisions(list ...bids.Decision) []*bids.BidDecision {
+	out := make([]*bids.BidDecision, 0, len(list))
+	for _, d := range list {
+		out = append(out, &bids.BidDecision{Decision: d})
+	}
+	return out
+}
+
+func TestResolve(t *testing.T) {
+	a, r := bids.Approved, bids.Rejected
+	cases := []struct {
+		name         string
+		decisions    []*bids.BidDecision
+		responsibles int
+		want         bids.Decision
+	}{
+		{"no decisions", decisions(), 5, ""},
+		{"short of the quorum", decisions(a, a), 5, ""},
+		{"quorum of three reached", decisions(a, a, a), 5, bids.Approved},
+		{"quorum is all of two responsibles", decisions(a), 2, ""},
+		{"two of two responsibles", decisions(a, a), 2, bids.Approved},
+		{"one responsible", decisions(a), 1, bids.Approved},
+		{"no responsibles still needs an approval", decisions(), 0, ""},
+		{"no responsibles and one approval", decisions(a), 0, bids.Approved},
+		{"reject alone", decisions(r), 5, bids.Rejected},
+		{"reject after approvals", decisions(a, a, r), 5, bids.Rejected},
+		{"reject wins over a quorum", decisions(a, a, a, r), 3, bids.Rejected},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := bids.Resolve(tc.decisions, tc.responsibles); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
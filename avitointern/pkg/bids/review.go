@@ -0,0 +1,11 @@
+package bids
+
+const MaxFeedbackLength = 1000
+
+type Review struct {
+	ReviewID    string `json:"id"`
+	Description string `json:"description"`
+	CreatedAt   string `json:"createdAt"` // RFC3339 format.
+	BidID       string `json:"-"`
+	Username    string `json:"-"`
+}
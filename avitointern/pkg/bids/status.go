@@ -0,0 +1,52 @@
+package bids
+
+import (
+	"net/http"
+
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/tenders"
+)
+
+// transitions lists the allowed status changes. A canceled bid is final.
+var transitions = map[Status][]Status{
+	Created:   {Published, Canceled},
+	Published: {Canceled},
+}
+
+func CanTransition(from, to Status) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrIllegalTransition = apperr.ErrIllegalTransition
+	// ErrClosed is returned for a change to a bid that can no longer change.
+	ErrClosed = apperr.New(apperr.CodeBidClosed, http.StatusConflict, "the bid can no longer be changed")
+)
+
+// CheckTransition returns an error matching ErrIllegalTransition when the
+// change is not in the table, a change to the same status included.
+func CheckTransition(from, to Status) error {
+	if !CanTransition(from, to) {
+		return ErrIllegalTransition.WithDetailf("cannot change bid status from %s to %s", from, to)
+	}
+	return nil
+}
+
+// CheckChange returns ErrClosed when the bid may no longer be edited or change
+// status: it was canceled or decided, or the tender it was made for is closed.
+func CheckChange(bid *Bid, tenderStatus tenders.Status) error {
+	switch {
+	case bid.Status == Canceled:
+		return ErrClosed.WithDetail("the bid is canceled")
+	case bid.Decision != "":
+		return ErrClosed.WithDetail("the bid has been decided")
+	case tenderStatus == tenders.Closed:
+		return ErrClosed.WithDetail("the tender is closed")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package bids_test
+
+import (
+	"errors"
+	"testing"
+
+	"avitointern/pkg/bids"
+	"avitointern/pkg/tenders"
+)
+
+func TestCheckTransition(t *testing.T) {
+	cases := []struct {
+		from, to bids.Status
+		allowed  bool
+	}{
+		{bids.Created, bids.Published, true},
+		{bids.Created, bids.Canceled, true},
+		{bids.Published, bids.Canceled, true},
+		{bids.Created, bids.Created, false},
+		{bids.Published, bids.Created, false},
+		{bids.Published, bids.Published, false},
+		{bids.Canceled, bids.Created, false},
+		{bids.Canceled, bids.Published, false},
+		{bids.Canceled, bids.Canceled, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.from)+" to "+string(tc.to), func(t *testing.T) {
+			err := bids.CheckTransition(tc.from, tc.to)
+			if tc.allowed && err != nil {
+				t.Errorf("got %v, want the transition allowed", err)
+			}
+			if !tc.allowed && !errors.Is(err, bids.ErrIllegalTransition) {
+				t.Errorf("got %v, want ErrIllegalTransition", err)
+			}
+		})
+	}
+}
+
+func TestCheckChange(t *testing.T) {
+	cases := []struct {
+		name   string
+		bid    bids.Status
+		tender tenders.Status
+		closed bool
+	}{
+		{"created bid", bids.Created, tenders.Published, false},
+		{"published bid", bids.Published, tenders.Published, false},
+		{"canceled bid", bids.Canceled, tenders.Published, true},
+		{"closed tender", bids.Published, tenders.Closed, true},
+		{"created bid on a closed tender", bids.Created, tenders.Closed, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := bids.CheckChange(&bids.Bid{Status: tc.bid}, tc.tender)
+			if tc.closed != errors.Is(err, bids.ErrClosed) {
+				t.Errorf("got %v, want closed %v", err, tc.closed)
+			}
+		})
+	}
+}
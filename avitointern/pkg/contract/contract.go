@@ -0,0 +1,424 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"avitointern/pkg/api"
+	"avitointern/pkg/bids"
+	"avitointern/pkg/database"
+	"avitointern/pkg/handlers"
+	"avitointern/pkg/mail"
+	"avitointern/pkg/openapi"
+	"avitointern/pkg/server"
+	"avitointern/pkg/session"
+	"avitointern/pkg/tenders"
+	"avitointern/pkg/token"
+	"avitointern/pkg/user"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	login          = "george"
+	password       = "qwer"
+	organizationID = "123e4567-e89b-12d3-a456-426614174000"
+)
+
+// order runs the operations as a scenario: the tender is edited and
+// published before bids are created, and bids are published before a
+// decision is submitted. Operations missing here run last, sorted by path.
+var order = []string{
+	"checkServer",
+	"getTenders",
+	"getUserTenders",
+	"createTender",
+	"getTenderStatus",
+	"editTender",
+	"rollbackTender",
+	"updateTenderStatus",
+	"createBid",
+	"getUserBids",
+	"getBidsForTender",
+	"getBidStatus",
+	"updateBidStatus",
+	"editBid",
+	"rollbackBid",
+	"submitBidFeedback",
+	"getBidReviews",
+	"submitBidDecision",
+}
+
+// overrides pins parameters whose first enum value would make the scenario
+// fail for reasons unrelated to the contract, e.g. an illegal transition.
+var overrides = map[string]map[string]interface{}{
+	"updateTenderStatus": {"status": string(tenders.Published)},
+	"updateBidStatus":    {"status": string(bids.Published)},
+	"submitBidDecision":  {"decision": "Approved"},
+}
+
+// Result is the outcome of one operation. An operation passes when it
+// answered with a documented 2xx status and a body that matches the schema.
+type Result struct {
+	OperationID string
+	Method      string
+	Path        string
+	Status      int
+	Problems    []string
+}
+
+func (res *Result) Passed() bool {
+	return len(res.Problems) == 0
+}
+
+// Suite is the API on an httptest server with in-memory storage, seeded with
+// a tender and a bid and logged in. Operations are called with values
+// generated from their parameter and body schemas.
+type Suite struct {
+	spec     *openapi.Spec
+	server   *httptest.Server
+	client   *http.Client
+	baseURL  string
+	fixtures map[string]interface{}
+}
+
+// Start brings the suite up; Close shuts the server down.
+func Start(ctx context.Context, spec *openapi.Spec, logger *zap.SugaredLogger) (*Suite, error) {
+	users := user.NewMemoryRepo()
+	states := handlers.NewTenderStates(users, logger)
+	db := database.NewMemoryDB(users)
+	db.States = states
+	keys := token.NewKeyset()
+	if err := keys.Add("contract", []byte(strings.Repeat("contract", 4))); err != nil {
+		return nil, err
+	}
+
+	sessions := session.NewMemoryStore()
+	srv := httptest.NewServer(server.Handler(server.Config{
+		DB:       db,
+		Users:    users,
+		Sessions: session.NewSessionsManager(sessions, users),
+		Tokens:   token.NewManager(keys, sessions),
+		Mailer:   mail.NewStub(logger),
+		States:   states,
+		Spec:     spec,
+		Logger:   logger,
+	}))
+
+	tenderID, bidID, err := seed(ctx, db)
+	if err != nil {
+		srv.Close()
+		return nil, fmt.Errorf("seed: %w", err)
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		srv.Close()
+		return nil, err
+	}
+	s := &Suite{
+		spec:   spec,
+		server: srv,
+		client: &http.Client{
+			Jar:     jar,
+			Timeout: 10 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		baseURL: srv.URL + api.V1Prefix,
+		fixtures: map[string]interface{}{
+			"tenderId":          tenderID,
+			"bidId":             bidID,
+			"organizationId":    organizationID,
+			"authorId":          organizationID,
+			"authorType":        string(bids.Organization),
+			"username":          login,
+			"creatorUsername":   login,
+			"authorUsername":    login,
+			"requesterUsername": login,
+			"version":           1,
+		},
+	}
+	if err = s.login(ctx); err != nil {
+		srv.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Suite) Close() {
+	s.server.Close()
+}
+
+// seed inserts the fixtures directly, so later operations do not depend on
+// the responses of the create endpoints being right.
+func seed(ctx context.Context, db database.Database) (string, string, error) {
+	now := time.Now().Format(time.RFC3339)
+	tender := &tenders.Tender{
+		TenderID:          uuid.New().String(),
+		TenderName:        "contract tender",
+		TenderDescription: "seeded by the contract suite",
+		ServiceType:       tenders.Construction,
+		Status:            tenders.Created,
+		OrganizationID:    organizationID,
+		Version:           1,
+		CreatedAt:         now,
+		Author:            login,
+	}
+	tender.Versions = map[int32]*tenders.TenderVer{
+		1: {
+			TenderName:        tender.TenderName,
+			TenderDescription: tender.TenderDescription,
+			ServiceType:       string(tender.ServiceType),
+			Version:           1,
+			Status:            tender.Status,
+			ModifiedBy:        login,
+			ModifiedAt:        now,
+		},
+	}
+	if _, err := db.InsertTender(ctx, tender); err != nil {
+		return "", "", err
+	}
+
+	bid := &bids.Bid{
+		BidID:           uuid.New().String(),
+		BidName:         "contract bid",
+		BidDescription:  "seeded by the contract suite",
+		Status:          bids.Created,
+		TenderID:        tender.TenderID,
+		AuthorType:      bids.Organization,
+		AuthorID:        organizationID,
+		Version:         1,
+		CreatedAt:       now,
+		CreatorUsername: login,
+	}
+	bid.Versions = map[int32]*bids.BidVer{
+		1: {
+			BidName:        bid.BidName,
+			BidDescription: bid.BidDescription,
+			Version:        1,
+			Status:         bid.Status,
+		},
+	}
+	if _, err := db.InsertBid(ctx, bid); err != nil {
+		return "", "", err
+	}
+	return tender.TenderID, bid.BidID, nil
+}
+
+func (s *Suite) login(ctx context.Context) error {
+	form := url.Values{"login": {login}, "password": {password}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("login: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Operation is an operation of the spec and the path template it is under.
+type Operation struct {
+	ID     string
+	Method string
+	Path   string
+	op     *openapi3.Operation
+	item   *openapi3.PathItem
+}
+
+// Operations lists every operation of the spec in the scenario order.
+func (s *Suite) Operations() []Operation {
+	rank := make(map[string]int, len(order))
+	for i, id := range order {
+		rank[id] = i
+	}
+
+	var ops []Operation
+	for path, item := range s.spec.Doc().Paths.Map() {
+		for method, op := range item.Operations() {
+			ops = append(ops, Operation{ID: op.OperationID, Method: method, Path: path, op: op, item: item})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		ri, iok := rank[ops[i].ID]
+		rj, jok := rank[ops[j].ID]
+		if iok != jok {
+			return iok
+		}
+		if iok {
+			return ri < rj
+		}
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops
+}
+
+// Call sends the operation once and checks the response against the spec.
+func (s *Suite) Call(ctx context.Context, o Operation) *Result {
+	res := &Result{OperationID: o.ID, Method: o.Method}
+
+	req, err := s.request(ctx, o)
+	if err != nil {
+		res.Problems = append(res.Problems, "build request: "+err.Error())
+		return res
+	}
+	res.Path = req.URL.RequestURI()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		res.Problems = append(res.Problems, err.Error())
+		return res
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		res.Problems = append(res.Problems, "read body: "+err.Error())
+		return res
+	}
+	res.Status = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		res.Problems = append(res.Problems, fmt.Sprintf("expected a 2xx status, got %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+
+	op, ok := s.spec.Operation(req)
+	if !ok {
+		res.Problems = append(res.Problems, "the request does not match its own operation")
+		return res
+	}
+	for _, fe := range op.ValidateResponse(ctx, resp.StatusCode, resp.Header, bytes.NewReader(body)) {
+		res.Problems = append(res.Problems, fe.Field+": "+fe.Reason)
+	}
+	return res
+}
+
+func (s *Suite) request(ctx context.Context, o Operation) (*http.Request, error) {
+	path := o.Path
+	query := url.Values{}
+	params := append(openapi3.Parameters{}, o.item.Parameters...)
+	params = append(params, o.op.Parameters...)
+	for _, ref := range params {
+		p := ref.Value
+		switch p.In {
+		case openapi3.ParameterInPath:
+			v := s.value(o.ID, p.Name, p.Schema)
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(fmt.Sprint(v)))
+		case openapi3.ParameterInQuery:
+			if !p.Required && !s.known(o.ID, p.Name) {
+				continue
+			}
+			v := s.value(o.ID, p.Name, p.Schema)
+			if list, ok := v.([]interface{}); ok {
+				for _, item := range list {
+					query.Add(p.Name, fmt.Sprint(item))
+				}
+				continue
+			}
+			query.Set(p.Name, fmt.Sprint(v))
+		}
+	}
+
+	target := s.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	var contentType string
+	if o.op.RequestBody != nil && o.op.RequestBody.Value != nil {
+		if media := o.op.RequestBody.Value.Content.Get("application/json"); media != nil {
+			data, err := json.Marshal(s.value(o.ID, "", media.Schema))
+			if err != nil {
+				return nil, err
+			}
+			body = bytes.NewReader(data)
+			contentType = "application/json"
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, o.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+func (s *Suite) known(opID, name string) bool {
+	if _, ok := overrides[opID][name]; ok {
+		return true
+	}
+	_, ok := s.fixtures[name]
+	return ok
+}
+
+// value picks a value for a parameter or property: an override, a fixture
+// with the same name, the first enum value, or a value of the schema type.
+func (s *Suite) value(opID, name string, ref *openapi3.SchemaRef) interface{} {
+	if v, ok := overrides[opID][name]; ok {
+		return v
+	}
+	if v, ok := s.fixtures[name]; ok {
+		return v
+	}
+	if ref == nil || ref.Value == nil {
+		return "contract"
+	}
+
+	schema := ref.Value
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	switch {
+	case schema.Type.Is(openapi3.TypeObject):
+		obj := make(map[string]interface{}, len(schema.Properties))
+		for prop, propRef := range schema.Properties {
+			if propRef.Value != nil && propRef.Value.ReadOnly {
+				continue
+			}
+			obj[prop] = s.value(opID, prop, propRef)
+		}
+		return obj
+	case schema.Type.Is(openapi3.TypeArray):
+		return []interface{}{s.value(opID, name, schema.Items)}
+	case schema.Type.Is(openapi3.TypeInteger), schema.Type.Is(openapi3.TypeNumber):
+		if schema.Min != nil && *schema.Min > 1 {
+			return int(*schema.Min)
+		}
+		return 1
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return true
+	}
+
+	str := "contract " + name
+	if schema.MaxLength != nil && uint64(len(str)) > *schema.MaxLength {
+		str = str[:*schema.MaxLength]
+	}
+	return str
+}
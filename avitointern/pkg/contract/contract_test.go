@@ -0,0 +1,46 @@
+package contract
+
+import (
+	"context"
+	"testing"
+
+	"avitointern/pkg/api"
+	"avitointern/pkg/openapi"
+
+	"go.uber.org/zap"
+)
+
+// knownGaps are operations that break the contract for reasons of their own;
+// each is skipped with the reason until it is fixed.
+var knownGaps = map[string]string{
+	"checkServer": "GET /ping dials http://localhost:8080 instead of answering, so it is 500 without a running server",
+	"rollbackBid": "PUT /bids/{bidId}/rollback/{version} has no route yet, it is 404",
+}
+
+func TestContract(t *testing.T) {
+	spec, err := openapi.LoadEmbedded(api.V1Prefix, api.Prefix)
+	if err != nil {
+		t.Fatalf("load spec: %v", err)
+	}
+
+	ctx := context.Background()
+	s, err := Start(ctx, spec, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer s.Close()
+
+	// The operations build on each other, so they run in order and not in
+	// parallel.
+	for _, op := range s.Operations() {
+		t.Run(op.ID, func(t *testing.T) {
+			if reason, ok := knownGaps[op.ID]; ok {
+				t.Skip(reason)
+			}
+			res := s.Call(ctx, op)
+			for _, problem := range res.Problems {
+				t.Errorf("%s %s -> %d: %s", res.Method, res.Path, res.Status, problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package database
+
+import (
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/bids"
+	"avitointern/pkg/paging"
+	"avitointern/pkg/tenders"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrBidDecided      = apperr.New(apperr.CodeBidDecided, http.StatusBadRequest, "decision on the bid has already been made")
+	ErrAlreadyDecided  = apperr.New(apperr.CodeAlreadyDecided, http.StatusBadRequest, "user has already submitted a decision")
+	ErrTenderNotActive = apperr.New(apperr.CodeTenderNotActive, http.StatusBadRequest, "tender is not published")
+	ErrBidNotPublished = apperr.New(apperr.CodeBidNotPublished, http.StatusBadRequest, "the bid is not published")
+	ErrBidNotFound     = apperr.ErrBidNotFound
+)
+
+const bidColumns = `bid_id, bid_name, bid_description, status, tender_id,
+				author_type, author_id, version, created_at, creator_username`
+
+// bidSelect adds the outcome, which only a decision sets, to bidColumns.
+const bidSelect = bidColumns + `, COALESCE(decision, '')`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// rollbackTx is deferred right after Begin; after a successful Commit it is a no-op.
+func rollbackTx(ctx context.Context, tx pgx.Tx) {
+	if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		log.Printf("rollback failed: %v", err)
+	}
+}
+
+func scanBid(row rowScanner) (*bids.Bid, error) {
+	var bid bids.Bid
+	var createdAt time.Time
+	err := row.Scan(&bid.BidID, &bid.BidName, &bid.BidDescription, &bid.Status, &bid.TenderID,
+		&bid.AuthorType, &bid.AuthorID, &bid.Version, &createdAt, &bid.CreatorUsername, &bid.Decision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBidNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	bid.CreatedAt = formatTime(createdAt)
+	return &bid, nil
+}
+
+func (m *SQLManager) InsertBid(ctx context.Context, bid *bids.Bid) (string, error) {
+	tx, err := m.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer rollbackTx(ctx, tx)
+
+	query := `INSERT INTO bids (` + bidColumns + `)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = tx.Exec(ctx, query,
+		bid.BidID, bid.BidName, bid.BidDescription, bid.Status, bid.TenderID,
+		bid.AuthorType, bid.AuthorID, bid.Version, bid.CreatedAt, bid.CreatorUsername)
+	if err != nil {
+		return "", err
+	}
+
+	query = `INSERT INTO bid_versions (bid_id, version, bid_name, bid_description, status)
+			 VALUES ($1, $2, $3, $4, $5)`
+	for _, version := range bid.Versions {
+		_, err = tx.Exec(ctx, query, bid.BidID, version.Version, version.BidName,
+			version.BidDescription, version.Status)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Println("err in tx.commit")
+		return "", err
+	}
+
+	return bid.BidID, nil
+}
+
+func (m *SQLManager) GetBidByID(ctx context.Context, bidID string) (*bids.Bid, error) {
+	query := `SELECT ` + bidSelect + ` FROM bids WHERE bid_id = $1`
+
+	bid, err := scanBid(m.Pool.QueryRow(ctx, query, bidID))
+	if err != nil {
+		return nil, err
+	}
+
+	queryVersions := `SELECT version, bid_name, bid_description, status FROM bid_versions WHERE bid_id = $1`
+	rows, err := m.Pool.Query(ctx, queryVersions, bidID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bid.Versions = make(map[int32]*bids.BidVer)
+	for rows.Next() {
+		var version bids.BidVer
+		err = rows.Scan(&version.Version, &version.BidName, &version.BidDescription, &version.Status)
+		if err != nil {
+			return nil, err
+		}
+		bid.Versions[version.Version] = &version
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	bid.Decisions, err = bidDecisions(ctx, m.Pool, bidID)
+	if err != nil {
+		return nil, err
+	}
+
+	return bid, nil
+}
+
+// queryer is satisfied by both *pgxpool.Pool and pgx.Tx.
+type queryer interface {
+	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
+}
+
+func bidDecisions(ctx context.Context, q queryer, bidID string) ([]*bids.BidDecision, error) {
+	query := `SELECT username, decision, created_at FROM bid_decisions WHERE bid_id = $1 ORDER BY created_at`
+	rows, err := q.Query(ctx, query, bidID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	decisions := make([]*bids.BidDecision, 0)
+	for rows.Next() {
+		var decision bids.BidDecision
+		var createdAt time.Time
+		err = rows.Scan(&decision.Username, &decision.Decision, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		decision.CreatedAt = formatTime(createdAt)
+		decisions = append(decisions, &decision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return decisions, nil
+}
+
+func (m *SQLManager) MyBids(ctx context.Context, limit, offset int32, username string, after *paging.Cursor) ([]*bids.Bid, error) {
+	where := &whereBuilder{}
+	where.conds = append(where.conds, "creator_username = "+where.arg(username))
+	return m.listBids(ctx, where, limit, offset, after)
+}
+
+func (m *SQLManager) BidsByTender(ctx context.Context, limit, offset int32, tenderID string, after *paging.Cursor) ([]*bids.Bid, error) {
+	where := &whereBuilder{}
+	where.conds = append(where.conds, "tender_id = "+where.arg(tenderID), "status = "+where.arg(bids.Published))
+	return m.listBids(ctx, where, limit, offset, after)
+}
+
+// listBids orders bids by name and ID. A non-nil after continues the listing
+// past the bid the cursor was made from.
+func (m *SQLManager) listBids(ctx context.Context, where *whereBuilder, limit, offset int32, after *paging.Cursor) ([]*bids.Bid, error) {
+	if after != nil {
+		where.conds = append(where.conds, "(bid_name, bid_id) > ("+where.arg(after.Value)+", "+where.arg(after.ID)+")")
+	}
+	query := `SELECT ` + bidSelect + ` FROM bids` + where.String() +
+		` ORDER BY bid_name, bid_id LIMIT ` + where.arg(limit) + ` OFFSET ` + where.arg(offset)
+
+	return m.queryBids(ctx, query, where.args...)
+}
+
+func (m *SQLManager) queryBids(ctx context.Context, query string, args ...interface{}) ([]*bids.Bid, error) {
+	rows, err := m.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bidsList := make([]*bids.Bid, 0)
+	for rows.Next() {
+		var bid *bids.Bid
+		bid, err = scanBid(rows)
+		if err != nil {
+			return nil, err
+		}
+		bidsList = append(bidsList, bid)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return bidsList, nil
+}
+
+func (m *SQLManager) UpdateBidStatus(ctx context.Context, bidID string, newStatus bids.Status) (*bids.Bid, error) {
+	return m.updateBid(ctx, bidID, func(bid *bids.Bid) error {
+		if err := bids.CheckTransition(bid.Status, newStatus); err != nil {
+			return err
+		}
+		bid.Status = newStatus
+		return nil
+	})
+}
+
+func (m *SQLManager) EditBid(ctx context.Context, bidID string, name, description string) (*bids.Bid, error) {
+	return m.updateBid(ctx, bidID, func(bid *bids.Bid) error {
+		bid.BidName = name
+		bid.BidDescription = description
+		return nil
+	})
+}
+
+// updateBid applies change to the locked bid. The tender row is locked too,
+// so a decision cannot close it between the check and the update.
+func (m *SQLManager) updateBid(ctx context.Context, bidID string, change func(bid *bids.Bid) error) (*bids.Bid, error) {
+	tx, err := m.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackTx(ctx, tx)
+
+	query := `SELECT ` + bidSelect + ` FROM bids WHERE bid_id = $1 FOR UPDATE`
+	bid, err := scanBid(tx.QueryRow(ctx, query, bidID))
+	if err != nil {
+		return nil, err
+	}
+
+	var tenderStatus tenders.Status
+	query = `SELECT status FROM tenders WHERE tender_id = $1 FOR SHARE`
+	if err = tx.QueryRow(ctx, query, bid.TenderID).Scan(&tenderStatus); err != nil {
+		return nil, err
+	}
+	if err = bids.CheckChange(bid, tenderStatus); err != nil {
+		return nil, err
+	}
+	if err = change(bid); err != nil {
+		return nil, err
+	}
+	bid.Version++
+
+	updateBidQuery := `UPDATE bids SET bid_name = $1, bid_description = $2, status = $3, version = $4 WHERE bid_id = $5`
+	_, err = tx.Exec(ctx, updateBidQuery, bid.BidName, bid.BidDescription, bid.Status, bid.Version, bid.BidID)
+	if err != nil {
+		return nil, err
+	}
+
+	insertVersionQuery := `INSERT INTO bid_versions (bid_id, version, bid_name, bid_description, status)
+	VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.Exec(ctx, insertVersionQuery, bid.BidID, bid.Version, bid.BidName, bid.BidDescription, bid.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Println("err in tx.commit")
+		return nil, err
+	}
+
+	return bid, nil
+}
+
+func (m *SQLManager) SubmitBidDecision(ctx context.Context, bidID string, decision *bids.BidDecision) (*bids.Bid, error) {
+	tx, err := m.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rollbackTx(ctx, tx)
+
+	query := `SELECT ` + bidSelect + ` FROM bids WHERE bid_id = $1 FOR UPDATE`
+	bid, err := scanBid(tx.QueryRow(ctx, query, bidID))
+	if err != nil {
+		return nil, err
+	}
+	if bid.Status != bids.Published {
+		return nil, ErrBidNotPublished
+	}
+	if bid.Decision != "" {
+		return nil, ErrBidDecided
+	}
+
+	var organizationID string
+	var tenderStatus tenders.Status
+	query = `SELECT organization_id, status FROM tenders WHERE tender_id = $1 FOR UPDATE`
+	err = tx.QueryRow(ctx, query, bid.TenderID).Scan(&organizationID, &tenderStatus)
+	if err != nil {
+		return nil, err
+	}
+	if tenderStatus != tenders.Published {
+		return nil, ErrTenderNotActive
+	}
+
+	var responsibles int
+	query = `SELECT COUNT(*) FROM organization_responsible WHERE organization_id = $1`
+	err = tx.QueryRow(ctx, query, organizationID).Scan(&responsibles)
+	if err != nil {
+		return nil, err
+	}
+
+	bid.Decisions, err = bidDecisions(ctx, tx, bidID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range bid.Decisions {
+		if d.Username == decision.Username {
+			return nil, ErrAlreadyDecided
+		}
+	}
+
+	query = `INSERT INTO bid_decisions (bid_id, username, decision, created_at) VALUES ($1, $2, $3, $4)`
+	_, err = tx.Exec(ctx, query, bidID, decision.Username, decision.Decision, decision.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	bid.Decisions = append(bid.Decisions, decision)
+
+	// The decision that resolves the bid records the outcome on it, under the
+	// same lock, so no decision can follow.
+	bid.Decision = bids.Resolve(bid.Decisions, responsibles)
+	if bid.Decision != "" {
+		query = `UPDATE bids SET decision = $1 WHERE bid_id = $2`
+		if _, err = tx.Exec(ctx, query, bid.Decision, bidID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The quorum closes the tender through the state machine like a manual
+	// close does; the hooks run after the commit.
+	var tr tenders.Transition
+	var closed *tenders.Tender
+	if bid.Decision == bids.Approved {
+		tr, closed, err = updateTenderStatusTx(ctx, tx, m.States, bid.TenderID, tenders.Closed, decision.Username, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Println("err in tx.commit")
+		return nil, err
+	}
+	if closed != nil {
+		m.States.Notify(ctx, tr, closed)
+	}
+
+	return bid, nil
+}
+
+func (m *SQLManager) InsertReview(ctx context.Context, review *bids.Review) error {
+	query := `INSERT INTO bid_reviews (review_id, bid_id, username, description, created_at)
+			  VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := m.Pool.Exec(ctx, query, review.ReviewID, review.BidID, review.Username,
+		review.Description, review.CreatedAt)
+	return err
+}
+
+func (m *SQLManager) ReviewsByAuthor(ctx context.Context, limit, offset int32, authorUsername string) ([]*bids.Review, error) {
+	query := `SELECT r.review_id, r.bid_id, r.username, r.description, r.created_at
+			  FROM bid_reviews r JOIN bids b ON b.bid_id = r.bid_id
+			  WHERE b.creator_username = $1
+			  ORDER BY r.created_at DESC LIMIT $2 OFFSET $3`
+
+	rows, err := m.Pool.Query(ctx, query, authorUsername, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make([]*bids.Review, 0)
+	for rows.Next() {
+		var review bids.Review
+		var createdAt time.Time
+		err = rows.Scan(&review.ReviewID, &review.BidID, &review.Username, &review.Description, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		review.CreatedAt = formatTime(createdAt)
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+func (m *SQLManager) HasBidOnTender(ctx context.Context, tenderID, authorUsername string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM bids WHERE tender_id = $1 AND creator_username = $2)`
+
+	var exists bool
+	err := m.Pool.QueryRow(ctx, query, tenderID, authorUsername).Scan(&exists)
+	return exists, err
+}
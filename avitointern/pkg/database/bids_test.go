@@ -0,0 +1,155 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"avitointern/pkg/bids"
+	"avitointern/pkg/database"
+	"avitointern/pkg/tenders"
+
+	"github.com/google/uuid"
+)
+
+// publishedBid stores a published tender with a bid in status on it. The
+// tender belongs to a fresh organization without responsibles, so a single
+// approval reaches the quorum.
+func publishedBid(ctx context.Context, t *testing.T, db database.Database, status bids.Status) *bids.Bid {
+	t.Helper()
+	tender := newTender()
+	if _, err := db.InsertTender(ctx, tender); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpdateTenderStatus(ctx, tender.TenderID, tenders.Published, "george", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	bid := newBid(tender.TenderID, "bid", status)
+	if _, err := db.InsertBid(ctx, bid); err != nil {
+		t.Fatal(err)
+	}
+	return bid
+}
+
+func newBid(tenderID, name string, status bids.Status) *bids.Bid {
+	bid := &bids.Bid{
+		BidID:           uuid.New().String(),
+		BidName:         name,
+		BidDescription:  "storage test",
+		Status:          status,
+		TenderID:        tenderID,
+		AuthorType:      bids.User,
+		AuthorID:        uuid.New().String(),
+		Version:         1,
+		CreatedAt:       time.Now().UTC().Truncate(time.Second).Format(time.RFC3339),
+		CreatorUsername: "alice",
+	}
+	bid.Versions = map[int32]*bids.BidVer{
+		1: {BidName: bid.BidName, BidDescription: bid.BidDescription, Version: 1, Status: status},
+	}
+	return bid
+}
+
+func decide(ctx context.Context, db database.Database, bidID, username string, decision bids.Decision) (*bids.Bid, error) {
+	return db.SubmitBidDecision(ctx, bidID, &bids.BidDecision{
+		Username:  username,
+		Decision:  decision,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func TestBidStorage(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(ctx context.Context, t *testing.T, db database.Database)
+	}{
+		{"unknown id", func(ctx context.Context, t *testing.T, db database.Database) {
+			id := uuid.New().String()
+			if _, err := db.GetBidByID(ctx, id); !errors.Is(err, database.ErrBidNotFound) {
+				t.Errorf("get: got %v, want ErrBidNotFound", err)
+			}
+			if _, err := db.UpdateBidStatus(ctx, id, bids.Published); !errors.Is(err, database.ErrBidNotFound) {
+				t.Errorf("status: got %v, want ErrBidNotFound", err)
+			}
+			if _, err := decide(ctx, db, id, "george", bids.Approved); !errors.Is(err, database.ErrBidNotFound) {
+				t.Errorf("decision: got %v, want ErrBidNotFound", err)
+			}
+		}},
+		{"illegal status change", func(ctx context.Context, t *testing.T, db database.Database) {
+			bid := publishedBid(ctx, t, db, bids.Published)
+			if _, err := db.UpdateBidStatus(ctx, bid.BidID, bids.Created); !errors.Is(err, bids.ErrIllegalTransition) {
+				t.Errorf("got %v, want ErrIllegalTransition", err)
+			}
+		}},
+		{"canceled bid", func(ctx context.Context, t *testing.T, db database.Database) {
+			bid := publishedBid(ctx, t, db, bids.Published)
+			if _, err := db.UpdateBidStatus(ctx, bid.BidID, bids.Canceled); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.EditBid(ctx, bid.BidID, "renamed", "d"); !errors.Is(err, bids.ErrClosed) {
+				t.Errorf("edit: got %v, want ErrClosed", err)
+			}
+		}},
+		{"decision on an unpublished bid", func(ctx context.Context, t *testing.T, db database.Database) {
+			bid := publishedBid(ctx, t, db, bids.Created)
+			if _, err := decide(ctx, db, bid.BidID, "george", bids.Approved); !errors.Is(err, database.ErrBidNotPublished) {
+				t.Errorf("got %v, want ErrBidNotPublished", err)
+			}
+		}},
+		{"approval", func(ctx context.Context, t *testing.T, db database.Database) {
+			bid := publishedBid(ctx, t, db, bids.Published)
+			decided, err := decide(ctx, db, bid.BidID, "george", bids.Approved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decided.Decision != bids.Approved {
+				t.Errorf("got decision %q, want Approved", decided.Decision)
+			}
+			stored, err := db.GetBidByID(ctx, bid.BidID)
+			if err != nil || stored.Decision != bids.Approved {
+				t.Errorf("stored: got %v, %v, want the decision recorded", stored, err)
+			}
+			tender, err := db.GetTenderByID(ctx, bid.TenderID)
+			if err != nil || tender.Status != tenders.Closed {
+				t.Errorf("tender: got %v, %v, want it closed", tender, err)
+			}
+			if _, err = decide(ctx, db, bid.BidID, "alice", bids.Rejected); !errors.Is(err, database.ErrBidDecided) {
+				t.Errorf("second decision: got %v, want ErrBidDecided", err)
+			}
+			if _, err = db.EditBid(ctx, bid.BidID, "renamed", "d"); !errors.Is(err, bids.ErrClosed) {
+				t.Errorf("edit: got %v, want ErrClosed", err)
+			}
+		}},
+		{"rejection", func(ctx context.Context, t *testing.T, db database.Database) {
+			bid := publishedBid(ctx, t, db, bids.Published)
+			decided, err := decide(ctx, db, bid.BidID, "george", bids.Rejected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decided.Decision != bids.Rejected {
+				t.Errorf("got decision %q, want Rejected", decided.Decision)
+			}
+			tender, err := db.GetTenderByID(ctx, bid.TenderID)
+			if err != nil || tender.Status != tenders.Published {
+				t.Errorf("tender: got %v, %v, want it still published", tender, err)
+			}
+			if _, err = db.UpdateBidStatus(ctx, bid.BidID, bids.Canceled); !errors.Is(err, bids.ErrClosed) {
+				t.Errorf("cancel: got %v, want ErrClosed", err)
+			}
+		}},
+	}
+
+	ctx := context.Background()
+	for _, st := range storages {
+		t.Run(st.name, func(t *testing.T) {
+			db := st.open(t)
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					tc.run(ctx, t, db)
+				})
+			}
+		})
+	}
+}
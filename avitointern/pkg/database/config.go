@@ -0,0 +1,127 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	DSN              string
+	MaxConns         int32
+	MinConns         int32
+	MaxConnIdleTime  time.Duration
+	StatementTimeout time.Duration
+}
+
+// Storage backends selected by the STORAGE environment variable.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+var loadEnvOnce sync.Once
+
+// LoadEnv loads .env into the environment once; variables that are already
+// set take precedence.
+func LoadEnv() {
+	loadEnvOnce.Do(func() {
+		if err := godotenv.Load(); err != nil {
+			log.Println("No .env file found, proceeding with environment variables")
+		}
+	})
+}
+
+// Storage returns the configured backend, StoragePostgres by default.
+func Storage() (string, error) {
+	LoadEnv()
+	switch storage := os.Getenv("STORAGE"); storage {
+	case "", StoragePostgres:
+		return StoragePostgres, nil
+	case StorageMemory:
+		return StorageMemory, nil
+	default:
+		return "", fmt.Errorf("STORAGE: unknown backend %q", storage)
+	}
+}
+
+// ConfigFromEnv reads the POSTGRES_* environment variables, loading them from
+// .env when the file exists. Pool sizing and the statement timeout are
+// optional: POSTGRES_MAX_CONNS, POSTGRES_MIN_CONNS, POSTGRES_MAX_CONN_IDLE_TIME
+// and POSTGRES_STATEMENT_TIMEOUT (Go durations, e.g. "5s").
+func ConfigFromEnv() (Config, error) {
+	LoadEnv()
+	postgresUsername := os.Getenv("POSTGRES_USERNAME")
+	postgresPassword := os.Getenv("POSTGRES_PASSWORD")
+	postgresHost := os.Getenv("POSTGRES_HOST")
+	postgresPort := os.Getenv("POSTGRES_PORT")
+	postgresDatabase := os.Getenv("POSTGRES_DATABASE")
+
+	cfg := Config{
+		DSN: fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+			postgresUsername, postgresPassword, postgresHost, postgresPort, postgresDatabase),
+		MaxConns:         10,
+		MinConns:         1,
+		MaxConnIdleTime:  5 * time.Minute,
+		StatementTimeout: 10 * time.Second,
+	}
+
+	var err error
+	if cfg.MaxConns, err = envInt32("POSTGRES_MAX_CONNS", cfg.MaxConns); err != nil {
+		return cfg, err
+	}
+	if cfg.MinConns, err = envInt32("POSTGRES_MIN_CONNS", cfg.MinConns); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxConnIdleTime, err = envDuration("POSTGRES_MAX_CONN_IDLE_TIME", cfg.MaxConnIdleTime); err != nil {
+		return cfg, err
+	}
+	if cfg.StatementTimeout, err = envDuration("POSTGRES_STATEMENT_TIMEOUT", cfg.StatementTimeout); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (cfg Config) poolConfig() (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	poolCfg.MaxConns = cfg.MaxConns
+	poolCfg.MinConns = cfg.MinConns
+	poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	if cfg.StatementTimeout > 0 {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+	return poolCfg, nil
+}
+
+func envInt32(name string, def int32) (int32, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return def, nil
+	}
+	num, err := strconv.ParseInt(val, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return int32(num), nil
+}
+
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
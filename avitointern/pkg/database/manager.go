@@ -1,111 +1,183 @@
 package database
 
 import (
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/bids"
+	"avitointern/pkg/organizations"
+	"avitointern/pkg/paging"
 	"avitointern/pkg/tenders"
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
+	"strings"
+	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"github.com/joho/godotenv"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// SQLManager stores everything in Postgres. States checks the tender
+// transitions it makes on its own, closing a tender by the bid quorum, and
+// runs their hooks.
 type SQLManager struct {
-	DB *sql.DB
+	Config Config
+	Pool   *pgxpool.Pool
+	States *tenders.StateMachine
 }
 
 type Database interface {
-	Init()
+	Init(ctx context.Context) error
 	Close()
-	InsertTender(tender *tenders.Tender) (string, error)
-	GetTenderByID(tenderID string) (*tenders.Tender, error)
-	GetQuery(limit, offset int32, serviceTypes []tenders.ServiceType) ([]*tenders.Tender, error)
-	My(limit, offset int32, author string) ([]*tenders.Tender, error)
-	UpdateTenderStatus(tenderID string, newStatus tenders.Status) (*tenders.Tender, error)
-	EditTender(tenderID string, name, description string, serviceType tenders.ServiceType) (*tenders.Tender, error)
-	Rollback(tenderID string, version int32) (*tenders.TenderVer, error)
+	Health(ctx context.Context) error
+	Stats() Stats
+	InsertTender(ctx context.Context, tender *tenders.Tender) (string, error)
+	GetTenderByID(ctx context.Context, tenderID string) (*tenders.Tender, error)
+	// GetVisibleTender returns ErrTenderNotFound for tenders hidden from viewer.
+	GetVisibleTender(ctx context.Context, tenderID string, viewer tenders.Viewer) (*tenders.Tender, error)
+	GetQuery(ctx context.Context, viewer tenders.Viewer, limit, offset int32, filter tenders.Filter) ([]*tenders.Tender, error)
+	My(ctx context.Context, viewer tenders.Viewer, limit, offset int32, author string, filter tenders.Filter) ([]*tenders.Tender, error)
+	CountTenders(ctx context.Context, viewer tenders.Viewer, filter tenders.Filter) (int64, error)
+	UpdateTenderStatus(ctx context.Context, tenderID string, newStatus tenders.Status, actor string, ifVersion int32) (*tenders.Tender, error)
+	EditTender(ctx context.Context, tenderID string, name, description string, serviceType tenders.ServiceType, actor string, ifVersion int32) (*tenders.Tender, error)
+	Rollback(ctx context.Context, tenderID string, version int32, actor string, ifVersion int32) (*tenders.Tender, error)
+	GetTenderVersions(ctx context.Context, tenderID string, limit, offset int32) ([]*tenders.TenderVer, error)
+	GetTenderVersion(ctx context.Context, tenderID string, version int32) (*tenders.TenderVer, error)
+
+	InsertOrganization(ctx context.Context, org *organizations.Organization, creatorID string) error
+	GetOrganizationByID(ctx context.Context, organizationID string) (*organizations.Organization, error)
+	UpdateOrganization(ctx context.Context, org *organizations.Organization) (*organizations.Organization, error)
+	OrganizationTenders(ctx context.Context, organizationID string, limit, offset int32) ([]*tenders.Tender, error)
+
+	InsertBid(ctx context.Context, bid *bids.Bid) (string, error)
+	GetBidByID(ctx context.Context, bidID string) (*bids.Bid, error)
+	MyBids(ctx context.Context, limit, offset int32, username string, after *paging.Cursor) ([]*bids.Bid, error)
+	BidsByTender(ctx context.Context, limit, offset int32, tenderID string, after *paging.Cursor) ([]*bids.Bid, error)
+	UpdateBidStatus(ctx context.Context, bidID string, newStatus bids.Status) (*bids.Bid, error)
+	EditBid(ctx context.Context, bidID string, name, description string) (*bids.Bid, error)
+	SubmitBidDecision(ctx context.Context, bidID string, decision *bids.BidDecision) (*bids.Bid, error)
+	InsertReview(ctx context.Context, review *bids.Review) error
+	ReviewsByAuthor(ctx context.Context, limit, offset int32, authorUsername string) ([]*bids.Review, error)
+	HasBidOnTender(ctx context.Context, tenderID, authorUsername string) (bool, error)
 }
 
+// Stats is a snapshot of the connection pool, reported by the health endpoint.
+type Stats struct {
+	TotalConns    int32 `json:"totalConns"`
+	IdleConns     int32 `json:"idleConns"`
+	AcquiredConns int32 `json:"acquiredConns"`
+	MaxConns      int32 `json:"maxConns"`
+}
+
+var (
+	ErrTenderNotFound  = apperr.ErrTenderNotFound
+	ErrVersionNotFound = apperr.ErrVersionNotFound
+	ErrVersionConflict = apperr.ErrVersionConflict
+	// ErrDuplicateTender is an insert with the ID of an existing tender.
+	ErrDuplicateTender = apperr.New(apperr.CodeDuplicateTender, http.StatusConflict, "a tender with this ID already exists")
+)
+
 var _ Database = &SQLManager{}
 
 func NewMemoryRepo() *SQLManager {
-	return &SQLManager{}
+	return &SQLManager{States: tenders.NewStateMachine()}
 }
 
-func (m *SQLManager) Init() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, proceeding with environment variables")
+// Init connects using Config, or the POSTGRES_* environment variables when
+// Config has no DSN.
+func (m *SQLManager) Init(ctx context.Context) error {
+	if m.Config.DSN == "" {
+		cfg, err := ConfigFromEnv()
+		if err != nil {
+			return err
+		}
+		m.Config = cfg
 	}
-	serverAddress := os.Getenv("SERVER_ADDRESS")
-	postgresConn := os.Getenv("POSTGRES_CONN")
-	postgresUsername := os.Getenv("POSTGRES_USERNAME")
-	postgresPassword := os.Getenv("POSTGRES_PASSWORD")
-	postgresHost := os.Getenv("POSTGRES_HOST")
-	postgresPort := os.Getenv("POSTGRES_PORT")
-	postgresDatabase := os.Getenv("POSTGRES_DATABASE")
 
-	fmt.Println(serverAddress, postgresConn)
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		postgresUsername, postgresPassword, postgresHost, postgresPort, postgresDatabase)
+	pool, err := Open(ctx, m.Config)
+	if err != nil {
+		return err
+	}
 
-	db, err := sql.Open("pgx", dsn)
+	m.Pool = pool
+	log.Println("Successfully connected to the database!")
+	return nil
+}
+
+// Open creates a connection pool and pings the database.
+func Open(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
+	poolCfg, err := cfg.poolConfig()
 	if err != nil {
-		log.Fatalf("Unable to connect to database: %v", err)
+		return nil, err
 	}
 
-	if err = db.Ping(); err != nil {
-		log.Fatalf("Unable to ping database: %v", err)
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, err
 	}
 
-	m.DB = db
-	log.Println("Successfully connected to the database!")
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+
+	return pool, nil
 }
 
 func (m *SQLManager) Close() {
-	if err := m.DB.Close(); err != nil {
-		log.Println("Error closing database connection:", err)
+	m.Pool.Close()
+}
+
+func (m *SQLManager) Health(ctx context.Context) error {
+	return m.Pool.Ping(ctx)
+}
+
+func (m *SQLManager) Stats() Stats {
+	stat := m.Pool.Stat()
+	return Stats{
+		TotalConns:    stat.TotalConns(),
+		IdleConns:     stat.IdleConns(),
+		AcquiredConns: stat.AcquiredConns(),
+		MaxConns:      stat.MaxConns(),
 	}
 }
 
-func (m *SQLManager) InsertTender(tender *tenders.Tender) (string, error) {
-	tx, err := m.DB.Begin()
+func (m *SQLManager) InsertTender(ctx context.Context, tender *tenders.Tender) (string, error) {
+	tx, err := m.Pool.Begin(ctx)
 	if err != nil {
 		return "", err
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			rollbackErr := tx.Rollback()
-			if rollbackErr != nil {
-				log.Printf("rollback failed: %v", rollbackErr)
-			}
-			panic(r)
-		}
-	}()
+	defer rollbackTx(ctx, tx)
 
 	query := `INSERT INTO tenders (tender_id, tender_name, tender_description, 
 				service_type, status, organization_id, version, created_at, author)
 			  	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
-	_, err = tx.Exec(query,
+	_, err = tx.Exec(ctx, query,
 		tender.TenderID, tender.TenderName, tender.TenderDescription,
 		tender.ServiceType, tender.Status, tender.OrganizationID,
 		tender.Version, tender.CreatedAt, tender.Author)
+	if isUniqueViolation(err) {
+		return "", ErrDuplicateTender
+	}
 	if err != nil {
 		return "", err
 	}
 
-	query = `INSERT INTO tender_versions (tender_id, version, tender_name, tender_description, service_type, status)
-			 VALUES ($1, $2, $3, $4, $5, $6)`
+	query = `INSERT INTO tender_versions (tender_id, version, tender_name, tender_description, service_type, status,
+				modified_by, modified_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 	for _, version := range tender.Versions {
-		_, err = tx.Exec(query, tender.TenderID, version.Version, version.TenderName,
-			version.TenderDescription, version.ServiceType, version.Status)
+		_, err = tx.Exec(ctx, query, tender.TenderID, version.Version, version.TenderName,
+			version.TenderDescription, version.ServiceType, version.Status, version.ModifiedBy, version.ModifiedAt)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	if err = tx.Commit(); err != nil {
+	if err = tx.Commit(ctx); err != nil {
 		log.Println("err in tx.commit")
 		return "", err
 	}
@@ -113,101 +185,252 @@ func (m *SQLManager) InsertTender(tender *tenders.Tender) (string, error) {
 	return tender.TenderID, nil
 }
 
-func (m *SQLManager) GetTenderByID(tenderID string) (*tenders.Tender, error) {
-	query := `SELECT tender_id, tender_name, tender_description, service_type, status, organization_id, version, created_at, author FROM tenders WHERE tender_id = $1`
+const tenderColumns = `tender_id, tender_name, tender_description, service_type, status, organization_id, version, created_at, author`
 
+func scanTender(row rowScanner) (*tenders.Tender, error) {
 	var tender tenders.Tender
-	err := m.DB.QueryRow(query, tenderID).Scan(&tender.TenderID, &tender.TenderName, &tender.TenderDescription, &tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.Version, &tender.CreatedAt, &tender.Author)
+	var createdAt time.Time
+	err := row.Scan(&tender.TenderID, &tender.TenderName, &tender.TenderDescription, &tender.ServiceType,
+		&tender.Status, &tender.OrganizationID, &tender.Version, &createdAt, &tender.Author)
 	if err != nil {
 		return nil, err
 	}
+	tender.CreatedAt = formatTime(createdAt)
+	return &tender, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+func (m *SQLManager) GetTenderByID(ctx context.Context, tenderID string) (*tenders.Tender, error) {
+	query := `SELECT ` + tenderColumns + ` FROM tenders WHERE tender_id = $1`
 
-	queryVersions := `SELECT version, tender_name, tender_description, service_type FROM tender_versions WHERE tender_id = $1`
-	rows, err := m.DB.Query(queryVersions, tenderID)
+	tender, err := scanTender(m.Pool.QueryRow(ctx, query, tenderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTenderNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	tender.Versions = make(map[int32]*tenders.TenderVer)
-	for rows.Next() {
-		var version tenders.TenderVer
-		err = rows.Scan(&version.Version, &version.TenderName, &version.TenderDescription, &version.ServiceType)
-		if err != nil {
-			return nil, err
-		}
-		tender.Versions[version.Version] = &version
+	queryVersions := `SELECT ` + tenderVersionColumns + ` FROM tender_versions WHERE tender_id = $1`
+	versions, err := queryTenderVersions(ctx, m.Pool, queryVersions, tenderID)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	tender.Versions = make(map[int32]*tenders.TenderVer, len(versions))
+	for _, version := range versions {
+		tender.Versions[version.Version] = version
 	}
 
-	return &tender, nil
+	return tender, nil
 }
 
-func (m *SQLManager) GetQuery(limit, offset int32, serviceTypes []tenders.ServiceType) ([]*tenders.Tender, error) {
-	var query string
-	var args []interface{}
-
-	query = `SELECT tender_id, tender_name, tender_description, service_type, status, organization_id, version, created_at, author 
-			 FROM tenders`
+func (m *SQLManager) GetVisibleTender(ctx context.Context, tenderID string, viewer tenders.Viewer) (*tenders.Tender, error) {
+	query := `SELECT ` + tenderColumns + ` FROM tenders WHERE tender_id = $1`
+	args := []interface{}{tenderID}
+	if cond, condArgs := visibleTo(viewer, len(args)+1); cond != "" {
+		query += " AND " + cond
+		args = append(args, condArgs...)
+	}
 
-	if len(serviceTypes) > 0 {
-		query += " WHERE service_type IN ("
-		for i, service := range serviceTypes {
-			if i > 0 {
-				query += ", "
-			}
-			query += fmt.Sprintf("$%d", len(args)+1)
-			args = append(args, service)
-		}
-		query += ")"
+	tender, err := scanTender(m.Pool.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTenderNotFound
 	}
+	return tender, err
+}
+
+// visibleTo returns the condition that limits tenders to those visible to the
+// viewer, with its placeholders numbered from n; it is empty for an admin.
+func visibleTo(viewer tenders.Viewer, n int) (string, []interface{}) {
+	if viewer.Admin {
+		return "", nil
+	}
+	cond := fmt.Sprintf(`(status = 'Published' OR organization_id IN (
+				SELECT r.organization_id::text FROM organization_responsible r
+				JOIN employee e ON e.id = r.user_id WHERE e.username = $%[1]d
+				UNION
+				SELECT v.organization_id::text FROM organization_viewer v
+				JOIN employee e ON e.id = v.user_id WHERE e.username = $%[1]d))`, n)
+	return cond, []interface{}{viewer.Username}
+}
+
+const tenderVersionColumns = `version, tender_name, tender_description, service_type, status,
+				modified_by, modified_at, rollback_of`
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
-	args = append(args, limit, offset)
+func scanTenderVersion(row rowScanner) (*tenders.TenderVer, error) {
+	var version tenders.TenderVer
+	var modifiedAt time.Time
+	var rollbackOf *int32
+	err := row.Scan(&version.Version, &version.TenderName, &version.TenderDescription,
+		&version.ServiceType, &version.Status, &version.ModifiedBy, &modifiedAt, &rollbackOf)
+	if err != nil {
+		return nil, err
+	}
+	version.ModifiedAt = formatTime(modifiedAt)
+	if rollbackOf != nil {
+		version.RollbackOf = *rollbackOf
+	}
+	return &version, nil
+}
 
-	rows, err := m.DB.Query(query, args...)
+func queryTenderVersions(ctx context.Context, q queryer, query string, args ...interface{}) ([]*tenders.TenderVer, error) {
+	rows, err := q.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tendersList []*tenders.Tender
+	versions := make([]*tenders.TenderVer, 0)
 	for rows.Next() {
-		var tender tenders.Tender
-		err = rows.Scan(&tender.TenderID, &tender.TenderName, &tender.TenderDescription,
-			&tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.Version, &tender.CreatedAt, &tender.Author)
+		var version *tenders.TenderVer
+		version, err = scanTenderVersion(rows)
 		if err != nil {
 			return nil, err
 		}
-		tendersList = append(tendersList, &tender)
+		versions = append(versions, version)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return tendersList, nil
+	return versions, nil
+}
+
+func (m *SQLManager) GetTenderVersions(ctx context.Context, tenderID string, limit, offset int32) ([]*tenders.TenderVer, error) {
+	query := `SELECT ` + tenderVersionColumns + ` FROM tender_versions WHERE tender_id = $1
+			  ORDER BY version LIMIT $2 OFFSET $3`
+
+	return queryTenderVersions(ctx, m.Pool, query, tenderID, limit, offset)
+}
+
+func (m *SQLManager) GetTenderVersion(ctx context.Context, tenderID string, version int32) (*tenders.TenderVer, error) {
+	query := `SELECT ` + tenderVersionColumns + ` FROM tender_versions WHERE tender_id = $1 AND version = $2`
+
+	ver, err := scanTenderVersion(m.Pool.QueryRow(ctx, query, tenderID, version))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ver, nil
+}
+
+// tenderSortColumns maps the API sort fields to columns. created_at is
+// compared at second precision, the precision of the RFC3339 value that ends
+// up in a cursor.
+var tenderSortColumns = map[tenders.SortField]string{
+	tenders.SortName:      "tender_name",
+	tenders.SortCreatedAt: "date_trunc('second', created_at)",
+	tenders.SortVersion:   "version",
 }
 
-func (m *SQLManager) My(limit, offset int32, author string) ([]*tenders.Tender, error) {
-	var query string
-	var args []interface{}
+// whereBuilder collects AND-ed conditions and their numbered bind parameters.
+type whereBuilder struct {
+	conds []string
+	args  []interface{}
+}
 
-	query = `SELECT tender_id, tender_name, tender_description, service_type, status, organization_id, version, created_at, author 
-			 FROM tenders`
+// arg adds a bind parameter and returns its placeholder.
+func (b *whereBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
 
-	if author != "" {
-		query += " WHERE author = $" + fmt.Sprintf("%d", len(args)+1)
-		args = append(args, author)
+func (b *whereBuilder) String() string {
+	if len(b.conds) == 0 {
+		return ""
 	}
+	return " WHERE " + strings.Join(b.conds, " AND ")
+}
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
-	args = append(args, limit, offset)
+// tenderWhere builds the conditions of a tender listing, without the cursor.
+func tenderWhere(viewer tenders.Viewer, filter tenders.Filter) *whereBuilder {
+	where := &whereBuilder{}
 
-	rows, err := m.DB.Query(query, args...)
+	if cond, condArgs := visibleTo(viewer, len(where.args)+1); cond != "" {
+		where.conds = append(where.conds, cond)
+		where.args = append(where.args, condArgs...)
+	}
+	if len(filter.ServiceTypes) > 0 {
+		serviceTypes := make([]string, 0, len(filter.ServiceTypes))
+		for _, service := range filter.ServiceTypes {
+			serviceTypes = append(serviceTypes, string(service))
+		}
+		where.conds = append(where.conds, "service_type = ANY("+where.arg(serviceTypes)+")")
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		where.conds = append(where.conds, "status = ANY("+where.arg(statuses)+")")
+	}
+	if filter.OrganizationID != "" {
+		where.conds = append(where.conds, "organization_id = "+where.arg(filter.OrganizationID))
+	}
+	if filter.Author != "" {
+		where.conds = append(where.conds, "author = "+where.arg(filter.Author))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		where.conds = append(where.conds, "created_at >= "+where.arg(filter.CreatedFrom))
+	}
+	if !filter.CreatedTo.IsZero() {
+		where.conds = append(where.conds, "created_at < "+where.arg(filter.CreatedTo))
+	}
+	if filter.NamePrefix != "" {
+		where.conds = append(where.conds, "starts_with(lower(tender_name), lower("+where.arg(filter.NamePrefix)+"))")
+	}
+	return where
+}
+
+// GetQuery lists the tenders matching the filter. With filter.After the
+// listing continues after the cursor, so inserts do not shift the pages.
+func (m *SQLManager) GetQuery(ctx context.Context, viewer tenders.Viewer, limit, offset int32, filter tenders.Filter) ([]*tenders.Tender, error) {
+	where := tenderWhere(viewer, filter)
+
+	column, ok := tenderSortColumns[filter.Sort]
+	if !ok {
+		column = tenderSortColumns[tenders.SortName]
+	}
+	direction, cmp := "ASC", ">"
+	if filter.Desc {
+		direction, cmp = "DESC", "<"
+	}
+	if filter.After != nil {
+		where.conds = append(where.conds, fmt.Sprintf("(%s, tender_id) %s (%s, %s)",
+			column, cmp, where.arg(filter.CursorValue()), where.arg(filter.After.ID)))
+	}
+
+	query := `SELECT ` + tenderColumns + ` FROM tenders` + where.String() +
+		fmt.Sprintf(" ORDER BY %[1]s %[2]s, tender_id %[2]s", column, direction)
+	query += " LIMIT " + where.arg(limit) + " OFFSET " + where.arg(offset)
+
+	return m.queryTenders(ctx, query, where.args...)
+}
+
+// CountTenders counts the tenders matching the filter, ignoring the cursor.
+func (m *SQLManager) CountTenders(ctx context.Context, viewer tenders.Viewer, filter tenders.Filter) (int64, error) {
+	where := tenderWhere(viewer, filter)
+
+	var count int64
+	err := m.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM tenders`+where.String(), where.args...).Scan(&count)
+	return count, err
+}
+
+// My lists the tenders created by author; filter.Author is overridden.
+func (m *SQLManager) My(ctx context.Context, viewer tenders.Viewer, limit, offset int32, author string, filter tenders.Filter) ([]*tenders.Tender, error) {
+	filter.Author = author
+	return m.GetQuery(ctx, viewer, limit, offset, filter)
+}
+
+func (m *SQLManager) queryTenders(ctx context.Context, query string, args ...interface{}) ([]*tenders.Tender, error) {
+	rows, err := m.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -215,13 +438,12 @@ func (m *SQLManager) My(limit, offset int32, author string) ([]*tenders.Tender,
 
 	var tendersList []*tenders.Tender
 	for rows.Next() {
-		var tender tenders.Tender
-		err = rows.Scan(&tender.TenderID, &tender.TenderName, &tender.TenderDescription,
-			&tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.Version, &tender.CreatedAt, &tender.Author)
+		var tender *tenders.Tender
+		tender, err = scanTender(rows)
 		if err != nil {
 			return nil, err
 		}
-		tendersList = append(tendersList, &tender)
+		tendersList = append(tendersList, tender)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -231,176 +453,191 @@ func (m *SQLManager) My(limit, offset int32, author string) ([]*tenders.Tender,
 	return tendersList, nil
 }
 
-func (m *SQLManager) UpdateTenderStatus(tenderID string, newStatus tenders.Status) (*tenders.Tender, error) {
-	tx, err := m.DB.Begin()
+func (m *SQLManager) UpdateTenderStatus(ctx context.Context, tenderID string, newStatus tenders.Status, actor string, ifVersion int32) (*tenders.Tender, error) {
+	tx, err := m.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			rollbackErr := tx.Rollback()
-			if rollbackErr != nil {
-				log.Printf("rollback failed: %v", rollbackErr)
-			}
-			panic(r)
-		}
-	}()
+	defer rollbackTx(ctx, tx)
 
-	var tender tenders.Tender
-	const querySel = `SELECT tender_id, tender_name, tender_description, service_type, status, organization_id, version, created_at, author
-              FROM tenders WHERE tender_id = $1`
-	err = tx.QueryRow(querySel, tenderID).Scan(&tender.TenderID, &tender.TenderName, &tender.TenderDescription,
-		&tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.Version, &tender.CreatedAt, &tender.Author)
+	_, tender, err := updateTenderStatusTx(ctx, tx, m.States, tenderID, newStatus, actor, ifVersion)
 	if err != nil {
-		log.Println("tx.QueryRow with select 1")
 		return nil, err
 	}
-	tender.Status = newStatus
 
-	const query = `SELECT COUNT(*) 
-			   FROM tender_versions WHERE tender_id = $1`
-	var len int
-	err = tx.QueryRow(query, tenderID).Scan(&len)
-	if err != nil {
-		log.Println("tx.QueryRow with select 2")
+	if err = tx.Commit(ctx); err != nil {
+		log.Println("err in tx.commit")
 		return nil, err
 	}
-	newVersion := len + 1
+
+	return tender, nil
+}
+
+// updateTenderStatusTx checks the transition with states against the locked
+// revision and stores it. The hooks are left to the caller, to run after the
+// commit with the returned transition.
+func updateTenderStatusTx(ctx context.Context, tx pgx.Tx, states *tenders.StateMachine, tenderID string, newStatus tenders.Status,
+	actor string, ifVersion int32) (tenders.Transition, *tenders.Tender, error) {
+	tender, err := lockTender(ctx, tx, tenderID, ifVersion)
+	if err != nil {
+		log.Println("tx.QueryRow with select 1")
+		return tenders.Transition{}, nil, err
+	}
+	tr := tenders.Transition{Tender: copyTender(tender), To: newStatus, Actor: actor}
+	if err = states.Check(ctx, tr); err != nil {
+		return tr, nil, err
+	}
+	tender.Status = newStatus
+	tender.Version++
 
 	updateTenderQuery := `UPDATE tenders SET status = $1, version = $2 WHERE tender_id = $3`
-	_, err = tx.Exec(updateTenderQuery, newStatus, newVersion, tender.TenderID)
+	_, err = tx.Exec(ctx, updateTenderQuery, tender.Status, tender.Version, tender.TenderID)
 	if err != nil {
 		log.Println("tx.Exec with updateTenderQuery")
-		return nil, err
+		return tr, nil, err
 	}
 
-	const insertVersionQuery = `INSERT INTO tender_versions (tender_id, version, tender_name, tender_description, service_type, status)
-	VALUES ($1, $2, $3, $4, $5, $6)`
-
-	_, err = tx.Exec(insertVersionQuery, tender.TenderID, newVersion, tender.TenderName,
-		tender.TenderDescription, tender.ServiceType, newStatus)
+	err = insertTenderVersion(ctx, tx, tender, actor, 0)
 	if err != nil {
 		log.Println("tx.Exec with insertVersionQuery")
-		return nil, err
-	}
-
-	if err = tx.Commit(); err != nil {
-		log.Println("err in tx.commit")
-		return nil, err
+		return tr, nil, err
 	}
 
-	return &tender, nil
+	return tr, tender, nil
 }
 
-func (m *SQLManager) EditTender(tenderID string, name, description string, serviceType tenders.ServiceType) (*tenders.Tender, error) {
-	tx, err := m.DB.Begin()
+func (m *SQLManager) EditTender(ctx context.Context, tenderID string, name, description string, serviceType tenders.ServiceType,
+	actor string, ifVersion int32) (*tenders.Tender, error) {
+	tx, err := m.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			rollbackErr := tx.Rollback()
-			if rollbackErr != nil {
-				log.Printf("rollback failed: %v", rollbackErr)
-			}
-			panic(r)
-		}
-	}()
+	defer rollbackTx(ctx, tx)
 
-	var tender tenders.Tender
-	query := `SELECT tender_id, tender_name, tender_description, service_type, status, organization_id, version, created_at, author
-              FROM tenders WHERE tender_id = $1`
-	err = tx.QueryRow(query, tenderID).Scan(&tender.TenderID, &tender.TenderName, &tender.TenderDescription,
-		&tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.Version, &tender.CreatedAt, &tender.Author)
+	tender, err := lockTender(ctx, tx, tenderID, ifVersion)
 	if err != nil {
 		return nil, err
 	}
 	tender.TenderName = name
 	tender.TenderDescription = description
 	tender.ServiceType = serviceType
-
-	query = `SELECT COUNT(*) 
-			   FROM tender_versions WHERE tender_id = $1`
-	var len int
-	err = tx.QueryRow(query, tenderID).Scan(&len)
-	if err != nil {
-		return nil, err
-	}
-	newVersion := len + 1
+	tender.Version++
 
 	updateTenderQuery := `UPDATE tenders SET version = $1, tender_name = $2, tender_description = $3, service_type = $4 WHERE tender_id = $5`
-	_, err = tx.Exec(updateTenderQuery, newVersion, tender.TenderName, tender.TenderDescription, tender.ServiceType, tender.TenderID)
+	_, err = tx.Exec(ctx, updateTenderQuery, tender.Version, tender.TenderName, tender.TenderDescription, tender.ServiceType, tender.TenderID)
 	if err != nil {
 		return nil, err
 	}
 
-	insertVersionQuery := `INSERT INTO tender_versions (tender_id, version, tender_name, tender_description, service_type, status)
-	VALUES ($1, $2, $3, $4, $5, $6)`
-
-	_, err = tx.Exec(insertVersionQuery, tender.TenderID, newVersion, tender.TenderName,
-		tender.TenderDescription, tender.ServiceType, tender.Status)
+	err = insertTenderVersion(ctx, tx, tender, actor, 0)
 	if err != nil {
 		return nil, err
 	}
-	if err = tx.Commit(); err != nil {
+	if err = tx.Commit(ctx); err != nil {
 		log.Println("err in tx.commit")
 		return nil, err
 	}
 
-	return &tender, nil
+	return tender, nil
 }
 
-func (m *SQLManager) Rollback(tenderID string, version int32) (*tenders.TenderVer, error) {
-	tx, err := m.DB.Begin()
+// Rollback copies the parameters of the requested version into a new revision.
+// The status is not rolled back: it only changes through UpdateTenderStatus.
+func (m *SQLManager) Rollback(ctx context.Context, tenderID string, version int32, actor string, ifVersion int32) (*tenders.Tender, error) {
+	tx, err := m.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			rollbackErr := tx.Rollback()
-			if rollbackErr != nil {
-				log.Printf("rollback failed: %v", rollbackErr)
-			}
-			panic(r)
-		}
-	}()
+	defer rollbackTx(ctx, tx)
 
-	var tender tenders.TenderVer
-	query := `SELECT tender_name, tender_description, service_type, status
-              FROM tender_versions WHERE tender_id = $1`
-	err = tx.QueryRow(query, tenderID).Scan(&tender.TenderName, &tender.TenderDescription,
-		&tender.ServiceType, &tender.Status)
+	tender, err := lockTender(ctx, tx, tenderID, ifVersion)
 	if err != nil {
 		return nil, err
 	}
 
-	query = `SELECT COUNT(*) 
-			   FROM tender_versions WHERE tender_id = $1`
-	var len int
-	err = tx.QueryRow(query, tenderID).Scan(&len)
+	query := `SELECT ` + tenderVersionColumns + ` FROM tender_versions WHERE tender_id = $1 AND version = $2`
+	snapshot, err := scanTenderVersion(tx.QueryRow(ctx, query, tenderID, version))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVersionNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	newVersion := len + 1
+	tender.TenderName = snapshot.TenderName
+	tender.TenderDescription = snapshot.TenderDescription
+	tender.ServiceType = tenders.ServiceType(snapshot.ServiceType)
+	tender.Version++
 
 	updateTenderQuery := `UPDATE tenders SET version = $1, tender_name = $2, tender_description = $3, service_type = $4 WHERE tender_id = $5`
-	_, err = tx.Exec(updateTenderQuery, newVersion, tender.TenderName, tender.TenderDescription, tender.ServiceType, tenderID)
+	_, err = tx.Exec(ctx, updateTenderQuery, tender.Version, tender.TenderName, tender.TenderDescription, tender.ServiceType, tenderID)
 	if err != nil {
 		return nil, err
 	}
 
-	insertVersionQuery := `INSERT INTO tender_versions (tender_id, version, tender_name, tender_description, service_type, status)
-	VALUES ($1, $2, $3, $4, $5, $6)`
-
-	_, err = tx.Exec(insertVersionQuery, tenderID, newVersion, tender.TenderName,
-		tender.TenderDescription, tender.ServiceType, tender.Status)
+	err = insertTenderVersion(ctx, tx, tender, actor, version)
 	if err != nil {
 		return nil, err
 	}
-	if err = tx.Commit(); err != nil {
+	if err = tx.Commit(ctx); err != nil {
 		log.Println("err in tx.commit")
 		return nil, err
 	}
 
-	return &tender, nil
+	return tender, nil
+}
+
+// lockTender selects the tender row FOR UPDATE, so concurrent mutations of the
+// same tender are serialized and each one allocates the next version. A non-zero
+// ifVersion must match the current version.
+func lockTender(ctx context.Context, tx pgx.Tx, tenderID string, ifVersion int32) (*tenders.Tender, error) {
+	query := `SELECT ` + tenderColumns + ` FROM tenders WHERE tender_id = $1 FOR UPDATE`
+	tender, err := scanTender(tx.QueryRow(ctx, query, tenderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTenderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ifVersion != 0 && tender.Version != ifVersion {
+		return nil, ErrVersionConflict
+	}
+	return tender, nil
+}
+
+// insertTenderVersion records the current state of the tender as a new revision.
+// A duplicate version violates the (tender_id, version) unique constraint.
+// rollbackOf is the source version for rollbacks and 0 otherwise.
+func insertTenderVersion(ctx context.Context, tx pgx.Tx, tender *tenders.Tender, actor string, rollbackOf int32) error {
+	const query = `INSERT INTO tender_versions (tender_id, version, tender_name, tender_description,
+				service_type, status, modified_by, modified_at, rollback_of)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	var source *int32
+	if rollbackOf > 0 {
+		source = &rollbackOf
+	}
+	_, err := tx.Exec(ctx, query, tender.TenderID, tender.Version, tender.TenderName, tender.TenderDescription,
+		tender.ServiceType, tender.Status, actor, time.Now(), source)
+	if isUniqueViolation(err) {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// Postgres SQLSTATE codes.
+const (
+	uniqueViolation           = "23505"
+	invalidTextRepresentation = "22P02"
+)
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// isInvalidUUID reports that Postgres could not cast an organization ID to
+// uuid, which means there is no such organization.
+func isInvalidUUID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
 }
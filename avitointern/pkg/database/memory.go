@@ -0,0 +1,625 @@
+package database
+
+import (
+	"avitointern/pkg/bids"
+	"avitointern/pkg/organizations"
+	"avitointern/pkg/paging"
+	"avitointern/pkg/tenders"
+	"avitointern/pkg/user"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// demoOrganizationID is the organization of the user seeded by user.NewMemoryRepo.
+const demoOrganizationID = "123e4567-e89b-12d3-a456-426614174000"
+
+// MemoryDB keeps everything in process memory. It follows the same versioning
+// rules as SQLManager and is used for offline runs (STORAGE=memory).
+// Users provides the organization responsibles for the bid decision quorum;
+// States checks the tender close the quorum makes and runs its hooks.
+type MemoryDB struct {
+	Users  user.UserRepo
+	States *tenders.StateMachine
+
+	mu            *sync.RWMutex
+	organizations map[string]*organizations.Organization
+	tenders       map[string]*tenders.Tender
+	tenderOrder   []string
+	bids          map[string]*bids.Bid
+	reviews       []*bids.Review
+}
+
+var _ Database = &MemoryDB{}
+
+func NewMemoryDB(users user.UserRepo) *MemoryDB {
+	now := formatTime(time.Now())
+	return &MemoryDB{
+		Users:  users,
+		States: tenders.NewStateMachine(),
+		mu:     &sync.RWMutex{},
+		organizations: map[string]*organizations.Organization{
+			demoOrganizationID: {
+				ID:        demoOrganizationID,
+				Name:      "Demo",
+				Type:      organizations.LLC,
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+		},
+		tenders: make(map[string]*tenders.Tender),
+		bids:    make(map[string]*bids.Bid),
+	}
+}
+
+func (m *MemoryDB) Init(_ context.Context) error {
+	return nil
+}
+
+func (m *MemoryDB) Close() {}
+
+func (m *MemoryDB) Health(_ context.Context) error {
+	return nil
+}
+
+func (m *MemoryDB) Stats() Stats {
+	return Stats{}
+}
+
+func (m *MemoryDB) InsertTender(_ context.Context, tender *tenders.Tender) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tenders[tender.TenderID]; ok {
+		return "", ErrDuplicateTender
+	}
+	m.tenders[tender.TenderID] = copyTender(tender)
+	m.tenderOrder = append(m.tenderOrder, tender.TenderID)
+
+	return tender.TenderID, nil
+}
+
+func (m *MemoryDB) GetTenderByID(_ context.Context, tenderID string) (*tenders.Tender, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tender, ok := m.tenders[tenderID]
+	if !ok {
+		return nil, ErrTenderNotFound
+	}
+	return copyTender(tender), nil
+}
+
+func (m *MemoryDB) GetVisibleTender(ctx context.Context, tenderID string, viewer tenders.Viewer) (*tenders.Tender, error) {
+	tender, err := m.GetTenderByID(ctx, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := m.visible(ctx, viewer, tender)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrTenderNotFound
+	}
+	return tender, nil
+}
+
+// visible mirrors the visibility condition of the Postgres queries.
+func (m *MemoryDB) visible(ctx context.Context, viewer tenders.Viewer, tender *tenders.Tender) (bool, error) {
+	if viewer.Admin || tender.Status == tenders.Published {
+		return true, nil
+	}
+	responsible, err := m.Users.IsResponsible(ctx, viewer.Username, tender.OrganizationID)
+	if err != nil || responsible {
+		return responsible, err
+	}
+	return m.Users.IsViewer(ctx, viewer.Username, tender.OrganizationID)
+}
+
+func (m *MemoryDB) GetQuery(ctx context.Context, viewer tenders.Viewer, limit, offset int32, filter tenders.Filter) ([]*tenders.Tender, error) {
+	list, err := m.filterVisibleTenders(ctx, viewer, filter.Match)
+	if err != nil {
+		return nil, err
+	}
+	return page(filter.Page(list), limit, offset), nil
+}
+
+func (m *MemoryDB) CountTenders(ctx context.Context, viewer tenders.Viewer, filter tenders.Filter) (int64, error) {
+	list, err := m.filterVisibleTenders(ctx, viewer, filter.Match)
+	return int64(len(list)), err
+}
+
+func (m *MemoryDB) My(ctx context.Context, viewer tenders.Viewer, limit, offset int32, author string, filter tenders.Filter) ([]*tenders.Tender, error) {
+	filter.Author = author
+	return m.GetQuery(ctx, viewer, limit, offset, filter)
+}
+
+func (m *MemoryDB) filterVisibleTenders(ctx context.Context, viewer tenders.Viewer,
+	match func(tender *tenders.Tender) bool) ([]*tenders.Tender, error) {
+	var err error
+	list := m.filterTenders(func(tender *tenders.Tender) bool {
+		if err != nil || !match(tender) {
+			return false
+		}
+		var visible bool
+		visible, err = m.visible(ctx, viewer, tender)
+		return visible
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// filterTenders returns copies of the matching tenders in insertion order.
+func (m *MemoryDB) filterTenders(match func(tender *tenders.Tender) bool) []*tenders.Tender {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var tendersList []*tenders.Tender
+	for _, id := range m.tenderOrder {
+		tender := m.tenders[id]
+		if !match(tender) {
+			continue
+		}
+		tendersList = append(tendersList, copyTender(tender))
+	}
+	return tendersList
+}
+
+func (m *MemoryDB) UpdateTenderStatus(ctx context.Context, tenderID string, newStatus tenders.Status, actor string, ifVersion int32) (*tenders.Tender, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, updated, err := m.updateTenderStatus(ctx, tenderID, newStatus, actor, ifVersion)
+	return updated, err
+}
+
+// updateTenderStatus is updateTenderStatusTx of SQLManager: the hooks are
+// left to the caller.
+func (m *MemoryDB) updateTenderStatus(ctx context.Context, tenderID string, newStatus tenders.Status, actor string,
+	ifVersion int32) (tenders.Transition, *tenders.Tender, error) {
+	// Missing tenders and version conflicts are reported by updateTender.
+	var tr tenders.Transition
+	stored, ok := m.tenders[tenderID]
+	if ok && (ifVersion == 0 || stored.Version == ifVersion) {
+		tr = tenders.Transition{Tender: copyTender(stored), To: newStatus, Actor: actor}
+		if err := m.States.Check(ctx, tr); err != nil {
+			return tr, nil, err
+		}
+	}
+	updated, err := m.updateTender(tenderID, actor, ifVersion, 0, func(tender *tenders.Tender) {
+		tender.Status = newStatus
+	})
+	return tr, updated, err
+}
+
+func (m *MemoryDB) EditTender(_ context.Context, tenderID string, name, description string, serviceType tenders.ServiceType,
+	actor string, ifVersion int32) (*tenders.Tender, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateTender(tenderID, actor, ifVersion, 0, func(tender *tenders.Tender) {
+		tender.TenderName = name
+		tender.TenderDescription = description
+		tender.ServiceType = serviceType
+	})
+}
+
+func (m *MemoryDB) Rollback(_ context.Context, tenderID string, version int32, actor string, ifVersion int32) (*tenders.Tender, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tender, ok := m.tenders[tenderID]
+	if !ok {
+		return nil, ErrTenderNotFound
+	}
+	if ifVersion != 0 && tender.Version != ifVersion {
+		return nil, ErrVersionConflict
+	}
+	snapshot, ok := tender.Versions[version]
+	if !ok {
+		return nil, ErrVersionNotFound
+	}
+
+	return m.updateTender(tenderID, actor, ifVersion, version, func(tender *tenders.Tender) {
+		tender.TenderName = snapshot.TenderName
+		tender.TenderDescription = snapshot.TenderDescription
+		tender.ServiceType = tenders.ServiceType(snapshot.ServiceType)
+	})
+}
+
+// updateTender applies change, bumps the version and records the new revision.
+// The caller holds the write lock.
+func (m *MemoryDB) updateTender(tenderID, actor string, ifVersion, rollbackOf int32,
+	change func(tender *tenders.Tender)) (*tenders.Tender, error) {
+	stored, ok := m.tenders[tenderID]
+	if !ok {
+		return nil, ErrTenderNotFound
+	}
+	if ifVersion != 0 && stored.Version != ifVersion {
+		return nil, ErrVersionConflict
+	}
+
+	change(stored)
+	stored.Version++
+	stored.Versions[stored.Version] = &tenders.TenderVer{
+		TenderName:        stored.TenderName,
+		TenderDescription: stored.TenderDescription,
+		ServiceType:       string(stored.ServiceType),
+		Version:           stored.Version,
+		Status:            stored.Status,
+		ModifiedBy:        actor,
+		ModifiedAt:        time.Now().Format(time.RFC3339),
+		RollbackOf:        rollbackOf,
+	}
+
+	return copyTender(stored), nil
+}
+
+func (m *MemoryDB) GetTenderVersions(_ context.Context, tenderID string, limit, offset int32) ([]*tenders.TenderVer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	versions := make([]*tenders.TenderVer, 0)
+	if tender, ok := m.tenders[tenderID]; ok {
+		for _, version := range tender.Versions {
+			ver := *version
+			versions = append(versions, &ver)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	return page(versions, limit, offset), nil
+}
+
+func (m *MemoryDB) GetTenderVersion(_ context.Context, tenderID string, version int32) (*tenders.TenderVer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tender, ok := m.tenders[tenderID]
+	if !ok {
+		return nil, nil
+	}
+	ver, ok := tender.Versions[version]
+	if !ok {
+		return nil, nil
+	}
+	verCopy := *ver
+	return &verCopy, nil
+}
+
+func (m *MemoryDB) InsertBid(_ context.Context, bid *bids.Bid) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tenders[bid.TenderID]; !ok {
+		return "", ErrTenderNotFound
+	}
+	m.bids[bid.BidID] = copyBid(bid)
+
+	return bid.BidID, nil
+}
+
+func (m *MemoryDB) GetBidByID(_ context.Context, bidID string) (*bids.Bid, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bid, ok := m.bids[bidID]
+	if !ok {
+		return nil, ErrBidNotFound
+	}
+	return copyBid(bid), nil
+}
+
+func (m *MemoryDB) MyBids(_ context.Context, limit, offset int32, username string, after *paging.Cursor) ([]*bids.Bid, error) {
+	return m.filterBids(limit, offset, after, func(bid *bids.Bid) bool {
+		return bid.CreatorUsername == username
+	}), nil
+}
+
+func (m *MemoryDB) BidsByTender(_ context.Context, limit, offset int32, tenderID string, after *paging.Cursor) ([]*bids.Bid, error) {
+	return m.filterBids(limit, offset, after, func(bid *bids.Bid) bool {
+		return bid.TenderID == tenderID && bid.Status == bids.Published
+	}), nil
+}
+
+// filterBids returns the matching bids ordered by name and ID and placed
+// after the cursor, as the SQL queries do.
+func (m *MemoryDB) filterBids(limit, offset int32, after *paging.Cursor, match func(bid *bids.Bid) bool) []*bids.Bid {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bidsList := make([]*bids.Bid, 0)
+	for _, bid := range m.bids {
+		if after != nil && (bid.BidName < after.Value || bid.BidName == after.Value && bid.BidID <= after.ID) {
+			continue
+		}
+		if match(bid) {
+			bidsList = append(bidsList, copyBid(bid))
+		}
+	}
+	sort.Slice(bidsList, func(i, j int) bool {
+		if bidsList[i].BidName != bidsList[j].BidName {
+			return bidsList[i].BidName < bidsList[j].BidName
+		}
+		return bidsList[i].BidID < bidsList[j].BidID
+	})
+
+	return page(bidsList, limit, offset)
+}
+
+func (m *MemoryDB) UpdateBidStatus(_ context.Context, bidID string, newStatus bids.Status) (*bids.Bid, error) {
+	return m.updateBid(bidID, func(bid *bids.Bid) error {
+		if err := bids.CheckTransition(bid.Status, newStatus); err != nil {
+			return err
+		}
+		bid.Status = newStatus
+		return nil
+	})
+}
+
+func (m *MemoryDB) EditBid(_ context.Context, bidID string, name, description string) (*bids.Bid, error) {
+	return m.updateBid(bidID, func(bid *bids.Bid) error {
+		bid.BidName = name
+		bid.BidDescription = description
+		return nil
+	})
+}
+
+// updateBid changes the bid in place, so change must fail before it touches
+// anything.
+func (m *MemoryDB) updateBid(bidID string, change func(bid *bids.Bid) error) (*bids.Bid, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bid, ok := m.bids[bidID]
+	if !ok {
+		return nil, ErrBidNotFound
+	}
+	tender, ok := m.tenders[bid.TenderID]
+	if !ok {
+		return nil, ErrTenderNotFound
+	}
+	if err := bids.CheckChange(bid, tender.Status); err != nil {
+		return nil, err
+	}
+	if err := change(bid); err != nil {
+		return nil, err
+	}
+	bid.Version++
+	bid.Versions[bid.Version] = &bids.BidVer{
+		BidName:        bid.BidName,
+		BidDescription: bid.BidDescription,
+		Version:        bid.Version,
+		Status:         bid.Status,
+	}
+
+	return copyBid(bid), nil
+}
+
+func (m *MemoryDB) SubmitBidDecision(ctx context.Context, bidID string, decision *bids.BidDecision) (*bids.Bid, error) {
+	// Deferred before the unlock, so the hooks of a quorum close run once the
+	// lock is released.
+	var tr tenders.Transition
+	var closed *tenders.Tender
+	defer func() {
+		if closed != nil {
+			m.States.Notify(ctx, tr, closed)
+		}
+	}()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bid, ok := m.bids[bidID]
+	if !ok {
+		return nil, ErrBidNotFound
+	}
+	tender, ok := m.tenders[bid.TenderID]
+	if !ok {
+		return nil, ErrTenderNotFound
+	}
+	if bid.Status != bids.Published {
+		return nil, ErrBidNotPublished
+	}
+	if bid.Decision != "" {
+		return nil, ErrBidDecided
+	}
+	if tender.Status != tenders.Published {
+		return nil, ErrTenderNotActive
+	}
+
+	var responsibles int
+	if m.Users != nil {
+		list, err := m.Users.ListResponsibles(ctx, tender.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		responsibles = len(list)
+	}
+	for _, d := range bid.Decisions {
+		if d.Username == decision.Username {
+			return nil, ErrAlreadyDecided
+		}
+	}
+
+	decisionCopy := *decision
+	bid.Decisions = append(bid.Decisions, &decisionCopy)
+
+	bid.Decision = bids.Resolve(bid.Decisions, responsibles)
+	if bid.Decision == bids.Approved {
+		var err error
+		if tr, closed, err = m.updateTenderStatus(ctx, bid.TenderID, tenders.Closed, decision.Username, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	return copyBid(bid), nil
+}
+
+func (m *MemoryDB) InsertReview(_ context.Context, review *bids.Review) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.bids[review.BidID]; !ok {
+		return ErrBidNotFound
+	}
+	reviewCopy := *review
+	m.reviews = append(m.reviews, &reviewCopy)
+
+	return nil
+}
+
+func (m *MemoryDB) ReviewsByAuthor(_ context.Context, limit, offset int32, authorUsername string) ([]*bids.Review, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reviews := make([]*bids.Review, 0)
+	for i := len(m.reviews) - 1; i >= 0; i-- {
+		review := m.reviews[i]
+		if bid, ok := m.bids[review.BidID]; ok && bid.CreatorUsername == authorUsername {
+			reviewCopy := *review
+			reviews = append(reviews, &reviewCopy)
+		}
+	}
+	sort.SliceStable(reviews, func(i, j int) bool { return reviews[i].CreatedAt > reviews[j].CreatedAt })
+
+	return page(reviews, limit, offset), nil
+}
+
+func (m *MemoryDB) HasBidOnTender(_ context.Context, tenderID, authorUsername string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, bid := range m.bids {
+		if bid.TenderID == tenderID && bid.CreatorUsername == authorUsername {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryDB) InsertOrganization(ctx context.Context, org *organizations.Organization, creatorID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkIdentifiers(org); err != nil {
+		return err
+	}
+
+	now := formatTime(time.Now())
+	created := *org
+	created.ID = uuid.New().String()
+	created.CreatedAt = now
+	created.UpdatedAt = now
+
+	if err := m.Users.AddResponsible(ctx, created.ID, creatorID); err != nil {
+		return err
+	}
+	m.organizations[created.ID] = &created
+	*org = created
+	return nil
+}
+
+func (m *MemoryDB) GetOrganizationByID(_ context.Context, organizationID string) (*organizations.Organization, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	org, ok := m.organizations[organizationID]
+	if !ok {
+		return nil, ErrOrganizationNotFound
+	}
+	found := *org
+	return &found, nil
+}
+
+func (m *MemoryDB) UpdateOrganization(_ context.Context, org *organizations.Organization) (*organizations.Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.organizations[org.ID]
+	if !ok {
+		return nil, ErrOrganizationNotFound
+	}
+	if err := m.checkIdentifiers(org); err != nil {
+		return nil, err
+	}
+
+	updated := *stored
+	updated.Name = org.Name
+	updated.Description = org.Description
+	updated.Type = org.Type
+	updated.INN = org.INN
+	updated.OGRN = org.OGRN
+	updated.KPP = org.KPP
+	updated.UpdatedAt = formatTime(time.Now())
+	m.organizations[org.ID] = &updated
+
+	result := updated
+	return &result, nil
+}
+
+// checkIdentifiers enforces the unique INN and OGRN indexes of the
+// organization table.
+func (m *MemoryDB) checkIdentifiers(org *organizations.Organization) error {
+	for id, other := range m.organizations {
+		if id == org.ID {
+			continue
+		}
+		if org.INN != "" && other.INN == org.INN {
+			return ErrDuplicateINN
+		}
+		if org.OGRN != "" && other.OGRN == org.OGRN {
+			return ErrDuplicateOGRN
+		}
+	}
+	return nil
+}
+
+func (m *MemoryDB) OrganizationTenders(_ context.Context, organizationID string, limit, offset int32) ([]*tenders.Tender, error) {
+	return page(m.filterTenders(func(tender *tenders.Tender) bool {
+		return tender.OrganizationID == organizationID
+	}), limit, offset), nil
+}
+
+// page applies LIMIT/OFFSET to an already filtered and ordered list.
+func page[T any](list []T, limit, offset int32) []T {
+	if offset < 0 || int(offset) >= len(list) {
+		return list[:0]
+	}
+	list = list[offset:]
+	if limit >= 0 && int(limit) < len(list) {
+		list = list[:limit]
+	}
+	return list
+}
+
+func copyTender(tender *tenders.Tender) *tenders.Tender {
+	tenderCopy := *tender
+	tenderCopy.Versions = make(map[int32]*tenders.TenderVer, len(tender.Versions))
+	for v, ver := range tender.Versions {
+		verCopy := *ver
+		tenderCopy.Versions[v] = &verCopy
+	}
+	return &tenderCopy
+}
+
+func copyBid(bid *bids.Bid) *bids.Bid {
+	bidCopy := *bid
+	bidCopy.Versions = make(map[int32]*bids.BidVer, len(bid.Versions))
+	for v, ver := range bid.Versions {
+		verCopy := *ver
+		bidCopy.Versions[v] = &verCopy
+	}
+	bidCopy.Decisions = make([]*bids.BidDecision, 0, len(bid.Decisions))
+	for _, decision := range bid.Decisions {
+		decisionCopy := *decision
+		bidCopy.Decisions = append(bidCopy.Decisions, &decisionCopy)
+	}
+	return &bidCopy
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/organizations"
+	"avitointern/pkg/tenders"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrOrganizationNotFound = apperr.ErrOrgNotFound
+	ErrDuplicateINN         = apperr.New(apperr.CodeDuplicateINN, http.StatusConflict, "an organization with this INN already exists")
+	ErrDuplicateOGRN        = apperr.New(apperr.CodeDuplicateOGRN, http.StatusConflict, "an organization with this OGRN already exists")
+)
+
+const organizationColumns = `id::text, name, COALESCE(description, ''), COALESCE(type::text, ''),
+	COALESCE(inn, ''), COALESCE(ogrn, ''), COALESCE(kpp, ''), created_at, updated_at`
+
+func scanOrganization(row rowScanner) (*organizations.Organization, error) {
+	var org organizations.Organization
+	var createdAt, updatedAt time.Time
+	err := row.Scan(&org.ID, &org.Name, &org.Description, &org.Type, &org.INN, &org.OGRN, &org.KPP,
+		&createdAt, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) || isInvalidUUID(err) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	org.CreatedAt = formatTime(createdAt)
+	org.UpdatedAt = formatTime(updatedAt)
+	return &org, nil
+}
+
+// duplicateIdentifier maps a unique violation on the identifier indexes to
+// the matching error.
+func duplicateIdentifier(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "organization_inn_key":
+		return ErrDuplicateINN
+	case "organization_ogrn_key":
+		return ErrDuplicateOGRN
+	}
+	return err
+}
+
+// InsertOrganization stores the organization and makes creatorID its first
+// responsible. ID and timestamps are filled in from the database.
+func (m *SQLManager) InsertOrganization(ctx context.Context, org *organizations.Organization, creatorID string) error {
+	tx, err := m.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollbackTx(ctx, tx)
+
+	query := `INSERT INTO organization (name, description, type, inn, ogrn, kpp)
+			  VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''))
+			  RETURNING ` + organizationColumns
+	created, err := scanOrganization(tx.QueryRow(ctx, query, org.Name, org.Description, string(org.Type),
+		org.INN, org.OGRN, org.KPP))
+	if err != nil {
+		return duplicateIdentifier(err)
+	}
+
+	query = `INSERT INTO organization_responsible (organization_id, user_id) VALUES ($1::uuid, $2::uuid)`
+	if _, err = tx.Exec(ctx, query, created.ID, creatorID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	*org = *created
+	return nil
+}
+
+func (m *SQLManager) GetOrganizationByID(ctx context.Context, organizationID string) (*organizations.Organization, error) {
+	query := `SELECT ` + organizationColumns + ` FROM organization WHERE id = $1::uuid`
+	return scanOrganization(m.Pool.QueryRow(ctx, query, organizationID))
+}
+
+func (m *SQLManager) UpdateOrganization(ctx context.Context, org *organizations.Organization) (*organizations.Organization, error) {
+	query := `UPDATE organization SET name = $1, description = $2, type = $3,
+			  inn = NULLIF($4, ''), ogrn = NULLIF($5, ''), kpp = NULLIF($6, ''), updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $7::uuid RETURNING ` + organizationColumns
+	updated, err := scanOrganization(m.Pool.QueryRow(ctx, query, org.Name, org.Description, string(org.Type),
+		org.INN, org.OGRN, org.KPP, org.ID))
+	if err != nil {
+		return nil, duplicateIdentifier(err)
+	}
+	return updated, nil
+}
+
+func (m *SQLManager) OrganizationTenders(ctx context.Context, organizationID string, limit, offset int32) ([]*tenders.Tender, error) {
+	query := `SELECT ` + tenderColumns + ` FROM tenders WHERE organization_id = $1
+			  ORDER BY created_at, tender_id LIMIT $2 OFFSET $3`
+	return m.queryTenders(ctx, query, organizationID, limit, offset)
+}
@@ -0,0 +1,91 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"avitointern/pkg/bids"
+	"avitointern/pkg/paging"
+	"avitointern/pkg/tenders"
+
+	"github.com/google/uuid"
+)
+
+// TestKeysetStability pages through the bids of a tender by cursor while
+// other bids are inserted: every bid that was there before the first page is
+// listed exactly once, and the listing never goes back in the sort order.
+func TestKeysetStability(t *testing.T) {
+	const seeded, inserted, limit = 30, 30, 4
+
+	ctx := context.Background()
+	for _, st := range storages {
+		t.Run(st.name, func(t *testing.T) {
+			db := st.open(t)
+			tender := newTender()
+			if _, err := db.InsertTender(ctx, tender); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.UpdateTenderStatus(ctx, tender.TenderID, tenders.Published, "george", 0); err != nil {
+				t.Fatal(err)
+			}
+
+			want := make(map[string]bool, seeded)
+			for i := 0; i < seeded; i++ {
+				bid := newBid(tender.TenderID, fmt.Sprintf("bid %02d", i*2), bids.Published)
+				if _, err := db.InsertBid(ctx, bid); err != nil {
+					t.Fatal(err)
+				}
+				want[bid.BidID] = true
+			}
+
+			// The new bids land between the seeded ones and on both sides of
+			// the pages already read.
+			insertErr := make(chan error, 1)
+			go func() {
+				defer close(insertErr)
+				for i := 0; i < inserted; i++ {
+					name := fmt.Sprintf("bid %02d %s", i*2+1, uuid.New().String()[:4])
+					if _, err := db.InsertBid(ctx, newBid(tender.TenderID, name, bids.Published)); err != nil {
+						insertErr <- err
+						return
+					}
+				}
+			}()
+
+			seen := make(map[string]bool)
+			var after *paging.Cursor
+			var last *bids.Bid
+			for {
+				list, err := db.BidsByTender(ctx, paging.Lookahead(limit), 0, tender.TenderID, after)
+				if err != nil {
+					t.Fatal(err)
+				}
+				list, more := paging.Trim(list, limit)
+				for _, bid := range list {
+					if seen[bid.BidID] {
+						t.Errorf("bid %q listed twice", bid.BidName)
+					}
+					seen[bid.BidID] = true
+					if last != nil && (bid.BidName < last.BidName || bid.BidName == last.BidName && bid.BidID <= last.BidID) {
+						t.Errorf("bid %q listed after %q", bid.BidName, last.BidName)
+					}
+					last = bid
+				}
+				if !more {
+					break
+				}
+				after = bids.CursorAfter(last)
+			}
+			if err := <-insertErr; err != nil {
+				t.Fatal(err)
+			}
+
+			for id := range want {
+				if !seen[id] {
+					t.Errorf("bid %s was not listed", id)
+				}
+			}
+		})
+	}
+}
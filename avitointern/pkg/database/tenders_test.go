@@ -0,0 +1,175 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"avitointern/pkg/database"
+	"avitointern/pkg/migrations"
+	"avitointern/pkg/tenders"
+	"avitointern/pkg/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+type storage struct {
+	name string
+	open func(t *testing.T) database.Database
+}
+
+// storages are the implementations every case runs against. Postgres is
+// used when TEST_POSTGRES_DSN is set and skipped otherwise; the migrations
+// are applied to it and every case works on tenders of its own, so the
+// database can be reused between runs.
+var storages = []storage{
+	{"memory", func(*testing.T) database.Database {
+		return database.NewMemoryDB(user.NewMemoryRepo())
+	}},
+	{"postgres", openPostgres},
+}
+
+func openPostgres(t *testing.T) database.Database {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	cfg, err := database.ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DSN = dsn
+
+	ctx := context.Background()
+	pg := database.NewMemoryRepo()
+	pg.Config = cfg
+	if err = pg.Init(ctx); err != nil {
+		t.Fatalf("connect to Postgres: %v", err)
+	}
+	t.Cleanup(pg.Close)
+
+	db := stdlib.OpenDBFromPool(pg.Pool)
+	defer db.Close()
+	if _, err = migrations.New(db).Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return pg
+}
+
+func newTender() *tenders.Tender {
+	now := time.Now().UTC().Truncate(time.Second).Format(time.RFC3339)
+	tender := &tenders.Tender{
+		TenderID:          uuid.New().String(),
+		TenderName:        "tender",
+		TenderDescription: "storage test",
+		ServiceType:       tenders.Construction,
+		Status:            tenders.Created,
+		OrganizationID:    uuid.New().String(),
+		Version:           1,
+		CreatedAt:         now,
+		Author:            "george",
+	}
+	tender.Versions = map[int32]*tenders.TenderVer{
+		1: {
+			TenderName:        tender.TenderName,
+			TenderDescription: tender.TenderDescription,
+			ServiceType:       string(tender.ServiceType),
+			Version:           1,
+			Status:            tender.Status,
+			ModifiedBy:        tender.Author,
+			ModifiedAt:        now,
+		},
+	}
+	return tender
+}
+
+func TestTenderStorage(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(ctx context.Context, t *testing.T, db database.Database)
+	}{
+		{"insert and get", func(ctx context.Context, t *testing.T, db database.Database) {
+			tender := newTender()
+			if _, err := db.InsertTender(ctx, tender); err != nil {
+				t.Fatal(err)
+			}
+			got, err := db.GetTenderByID(ctx, tender.TenderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.TenderName != tender.TenderName || got.Status != tenders.Created || got.Version != 1 {
+				t.Errorf("got %+v, want the inserted tender", got)
+			}
+		}},
+		{"duplicate id", func(ctx context.Context, t *testing.T, db database.Database) {
+			tender := newTender()
+			if _, err := db.InsertTender(ctx, tender); err != nil {
+				t.Fatal(err)
+			}
+			_, err := db.InsertTender(ctx, tender)
+			if !errors.Is(err, database.ErrDuplicateTender) {
+				t.Errorf("second insert: got %v, want ErrDuplicateTender", err)
+			}
+		}},
+		{"unknown id", func(ctx context.Context, t *testing.T, db database.Database) {
+			_, err := db.GetTenderByID(ctx, uuid.New().String())
+			if !errors.Is(err, database.ErrTenderNotFound) {
+				t.Errorf("got %v, want ErrTenderNotFound", err)
+			}
+		}},
+		{"status change", func(ctx context.Context, t *testing.T, db database.Database) {
+			tender := newTender()
+			if _, err := db.InsertTender(ctx, tender); err != nil {
+				t.Fatal(err)
+			}
+			updated, err := db.UpdateTenderStatus(ctx, tender.TenderID, tenders.Published, "george", 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Status != tenders.Published || updated.Version != 2 {
+				t.Errorf("got status %v version %d, want Published 2", updated.Status, updated.Version)
+			}
+		}},
+		{"illegal transition", func(ctx context.Context, t *testing.T, db database.Database) {
+			tender := newTender()
+			if _, err := db.InsertTender(ctx, tender); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.UpdateTenderStatus(ctx, tender.TenderID, tenders.Closed, "george", 0); err != nil {
+				t.Fatal(err)
+			}
+			_, err := db.UpdateTenderStatus(ctx, tender.TenderID, tenders.Published, "george", 0)
+			if !errors.Is(err, tenders.ErrIllegalTransition) {
+				t.Errorf("got %v, want ErrIllegalTransition", err)
+			}
+		}},
+		{"stale version", func(ctx context.Context, t *testing.T, db database.Database) {
+			tender := newTender()
+			if _, err := db.InsertTender(ctx, tender); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.EditTender(ctx, tender.TenderID, "renamed", "", tenders.Delivery, "george", 1); err != nil {
+				t.Fatal(err)
+			}
+			_, err := db.UpdateTenderStatus(ctx, tender.TenderID, tenders.Published, "george", 1)
+			if !errors.Is(err, database.ErrVersionConflict) {
+				t.Errorf("got %v, want ErrVersionConflict", err)
+			}
+		}},
+	}
+
+	ctx := context.Background()
+	for _, st := range storages {
+		t.Run(st.name, func(t *testing.T) {
+			db := st.open(t)
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					tc.run(ctx, t, db)
+				})
+			}
+		})
+	}
+}
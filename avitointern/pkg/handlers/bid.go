@@ -0,0 +1,535 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	v1 "avitointern/pkg/api/v1"
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/authz"
+	"avitointern/pkg/bids"
+	"avitointern/pkg/database"
+	"avitointern/pkg/paging"
+	"avitointern/pkg/session"
+	"avitointern/pkg/tenders"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type BidsHandler struct {
+	SQL    database.Database
+	Authz  *authz.Policy
+	Logger *zap.SugaredLogger
+}
+
+func (h *BidsHandler) New(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	var createRequest struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		TenderID    *string `json:"tenderId"`
+		AuthorType  *string `json:"authorType"`
+		AuthorID    *string `json:"authorId"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&createRequest); err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if createRequest.Name == nil || createRequest.Description == nil || createRequest.TenderID == nil ||
+		createRequest.AuthorType == nil || createRequest.AuthorID == nil {
+		h.errSend(w, r, "missing required fields", http.StatusBadRequest)
+		return
+	}
+	if !bids.ValidAuthorType(*createRequest.AuthorType) {
+		h.errSend(w, r, "invalid format authorType", http.StatusBadRequest)
+		return
+	}
+
+	bid := &bids.Bid{
+		BidID:           uuid.New().String(),
+		BidName:         *createRequest.Name,
+		BidDescription:  *createRequest.Description,
+		Status:          bids.Created,
+		TenderID:        *createRequest.TenderID,
+		AuthorType:      bids.AuthorType(*createRequest.AuthorType),
+		AuthorID:        *createRequest.AuthorID,
+		Version:         1,
+		CreatedAt:       time.Now().Format(time.RFC3339), // RFC3339 format.
+		CreatorUsername: sess.User.Username,
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.BidCreate, bidAuthorResource(bid)) {
+		return
+	}
+
+	tender, err := h.SQL.GetTenderByID(r.Context(), bid.TenderID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if tender.Status != tenders.Published {
+		h.fail(w, r, apperr.ErrForbidden.WithDetail("the tender is not open for bids"))
+		return
+	}
+
+	bid.Versions = map[int32]*bids.BidVer{
+		bid.Version: {
+			BidName:        bid.BidName,
+			BidDescription: bid.BidDescription,
+			Version:        bid.Version,
+			Status:         bid.Status,
+		},
+	}
+
+	lastID, err := h.SQL.InsertBid(r.Context(), bid)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewBid(bid)); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	h.Logger.Infof("Insert bid with id LastInsertId: %v", lastID)
+}
+
+func (h *BidsHandler) My(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, reason := parsePage(r)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
+		return
+	}
+
+	sess, ok := h.sessionForUsername(w, r)
+	if !ok {
+		return
+	}
+
+	cursor, ok := h.bidCursor(w, r, offset)
+	if !ok {
+		return
+	}
+
+	bidsList, err := h.SQL.MyBids(r.Context(), paging.Lookahead(limit), offset, sess.User.Username, cursor)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = sendBidList(w, bidsList, limit); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+}
+
+func (h *BidsHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, reason := parsePage(r)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
+		return
+	}
+
+	sess, ok := h.sessionForUsername(w, r)
+	if !ok {
+		return
+	}
+
+	tenderID := mux.Vars(r)["tenderID"]
+	tender, err := h.SQL.GetTenderByID(r.Context(), tenderID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.BidView, tenderResource(tender)) {
+		return
+	}
+
+	cursor, ok := h.bidCursor(w, r, offset)
+	if !ok {
+		return
+	}
+
+	bidsList, err := h.SQL.BidsByTender(r.Context(), paging.Lookahead(limit), offset, tenderID, cursor)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = sendBidList(w, bidsList, limit); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+}
+
+func (h *BidsHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, ok := h.sessionForUsername(w, r)
+	if !ok {
+		return
+	}
+
+	bid, ok := h.bidFromVars(w, r)
+	if !ok {
+		return
+	}
+
+	// The author sees the bid in any status, the tender organization only
+	// once it is published.
+	res := bidAuthorResource(bid)
+	author, err := h.Authz.Allowed(r.Context(), sess.User, authz.BidView, res)
+	if err != nil {
+		h.fail(w, r, fmt.Errorf("permission lookup: %w", err))
+		return
+	}
+	if !author {
+		if bid.Status == bids.Published {
+			tender, err := h.SQL.GetTenderByID(r.Context(), bid.TenderID)
+			if err != nil {
+				h.fail(w, r, err)
+				return
+			}
+			res = tenderResource(tender)
+		}
+		if !authorize(w, r, h.Authz, h.Logger, sess, authz.BidView, res) {
+			return
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(bid.Status); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	h.Logger.Infof("Bid status by ID: %v", bid.Status)
+}
+
+func (h *BidsHandler) EditStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status := r.URL.Query().Get("status")
+	if !bids.ValidStatus(status) {
+		h.errSend(w, r, "invalid format status", http.StatusBadRequest)
+		return
+	}
+
+	sess, ok := h.sessionForUsername(w, r)
+	if !ok {
+		return
+	}
+
+	bid, ok := h.bidFromVars(w, r)
+	if !ok {
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.BidEdit, bidAuthorResource(bid)) {
+		return
+	}
+
+	bid, err := h.SQL.UpdateBidStatus(r.Context(), bid.BidID, bids.Status(status))
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewBid(bid)); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	h.Logger.Infof("Edit bid status by ID: %v", bid.Status)
+}
+
+func (h *BidsHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, ok := h.sessionForUsername(w, r)
+	if !ok {
+		return
+	}
+
+	var updateRequest struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	bid, ok := h.bidFromVars(w, r)
+	if !ok {
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.BidEdit, bidAuthorResource(bid)) {
+		return
+	}
+
+	if updateRequest.Name == nil && updateRequest.Description == nil {
+		h.errSend(w, r, "nothing to update", http.StatusBadRequest)
+		return
+	}
+	if updateRequest.Name != nil {
+		bid.BidName = *updateRequest.Name
+	}
+	if updateRequest.Description != nil {
+		bid.BidDescription = *updateRequest.Description
+	}
+
+	bid, err := h.SQL.EditBid(r.Context(), bid.BidID, bid.BidName, bid.BidDescription)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewBid(bid)); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	h.Logger.Infof("EditBid PATCH by ID: %v", bid.BidID)
+}
+
+func (h *BidsHandler) SubmitDecision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	decision := r.URL.Query().Get("decision")
+	if !bids.ValidDecision(decision) {
+		h.errSend(w, r, "invalid format decision", http.StatusBadRequest)
+		return
+	}
+
+	sess, ok := h.sessionForUsername(w, r)
+	if !ok {
+		return
+	}
+
+	// Whether the bid still takes decisions is checked by the storage under
+	// the bid lock.
+	bid, ok := h.bidFromVars(w, r)
+	if !ok {
+		return
+	}
+
+	tender, err := h.SQL.GetTenderByID(r.Context(), bid.TenderID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.BidDecide, tenderResource(tender)) {
+		return
+	}
+
+	bid, err = h.SQL.SubmitBidDecision(r.Context(), bid.BidID, &bids.BidDecision{
+		Username:  sess.User.Username,
+		Decision:  bids.Decision(decision),
+		CreatedAt: time.Now().Format(time.RFC3339), // RFC3339 format.
+	})
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewBid(bid)); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	h.Logger.Infof("Decision %v on bid %v by %v", decision, bid.BidID, sess.User.Username)
+}
+
+func (h *BidsHandler) Feedback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	feedback := r.URL.Query().Get("bidFeedback")
+	if feedback == "" || utf8.RuneCountInString(feedback) > bids.MaxFeedbackLength {
+		h.errSend(w, r, "invalid format bidFeedback", http.StatusBadRequest)
+		return
+	}
+
+	sess, ok := h.sessionForUsername(w, r)
+	if !ok {
+		return
+	}
+
+	bid, ok := h.bidFromVars(w, r)
+	if !ok {
+		return
+	}
+	if bid.Status != bids.Published {
+		h.fail(w, r, database.ErrBidNotPublished)
+		return
+	}
+
+	tender, err := h.SQL.GetTenderByID(r.Context(), bid.TenderID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.BidFeedback, tenderResource(tender)) {
+		return
+	}
+
+	review := &bids.Review{
+		ReviewID:    uuid.New().String(),
+		Description: feedback,
+		CreatedAt:   time.Now().Format(time.RFC3339), // RFC3339 format.
+		BidID:       bid.BidID,
+		Username:    sess.User.Username,
+	}
+	if err = h.SQL.InsertReview(r.Context(), review); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewBid(bid)); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	h.Logger.Infof("Review %v on bid %v by %v", review.ReviewID, bid.BidID, sess.User.Username)
+}
+
+func (h *BidsHandler) Reviews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, reason := parsePage(r)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
+		return
+	}
+
+	authorUsername := r.URL.Query().Get("authorUsername")
+	if authorUsername == "" {
+		h.errSend(w, r, "invalid format authorUsername", http.StatusBadRequest)
+		return
+	}
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil || r.URL.Query().Get("requesterUsername") != sess.User.Username {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	tenderID := mux.Vars(r)["tenderID"]
+	tender, err := h.SQL.GetTenderByID(r.Context(), tenderID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.BidReviews, tenderResource(tender)) {
+		return
+	}
+
+	hasBid, err := h.SQL.HasBidOnTender(r.Context(), tenderID, authorUsername)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if !hasBid {
+		h.fail(w, r, apperr.ErrNotFound.WithDetail("the author has no bids on the tender"))
+		return
+	}
+
+	reviews, err := h.SQL.ReviewsByAuthor(r.Context(), limit, offset, authorUsername)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewBidReviews(reviews)); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+}
+
+func (h *BidsHandler) sessionForUsername(w http.ResponseWriter, r *http.Request) (*session.Session, bool) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil || r.URL.Query().Get("username") != sess.User.Username {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return nil, false
+	}
+	return sess, true
+}
+
+func (h *BidsHandler) bidFromVars(w http.ResponseWriter, r *http.Request) (*bids.Bid, bool) {
+	bid, err := h.SQL.GetBidByID(r.Context(), mux.Vars(r)["bidID"])
+	if err != nil {
+		h.fail(w, r, err)
+		return nil, false
+	}
+	return bid, true
+}
+
+// bidAuthorResource is the bid as owned by its author: the user itself or the
+// authoring organization.
+func bidAuthorResource(bid *bids.Bid) authz.Resource {
+	if bid.AuthorType == bids.Organization {
+		return authz.Resource{OrganizationID: bid.AuthorID}
+	}
+	return authz.Resource{OwnerID: bid.AuthorID}
+}
+
+func tenderResource(tender *tenders.Tender) authz.Resource {
+	return authz.Resource{OrganizationID: tender.OrganizationID}
+}
+
+// authorize checks perm on the resource for the session user. It answers 500
+// when the role lookup failed and 403 when the policy denied the action.
+func authorize(w http.ResponseWriter, r *http.Request, policy *authz.Policy, logger *zap.SugaredLogger,
+	sess *session.Session, perm authz.Permission, res authz.Resource) bool {
+	err := policy.Authorize(r.Context(), sess.User, perm, res)
+	switch {
+	case errors.Is(err, authz.ErrDenied):
+		// The reason stays in the log; clients get the generic denial.
+		fail(w, r, logger, authz.ErrDenied)
+		return false
+	case err != nil:
+		fail(w, r, logger, fmt.Errorf("permission lookup: %w", err))
+		return false
+	}
+	return true
+}
+
+func (h *BidsHandler) errSend(w http.ResponseWriter, r *http.Request, reason string, status int) {
+	sendError(w, r, h.Logger, reason, status)
+}
+
+func (h *BidsHandler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	fail(w, r, h.Logger, err)
+}
+
+// bidCursor reads the cursor query parameter of a bid listing.
+func (h *BidsHandler) bidCursor(w http.ResponseWriter, r *http.Request, offset int32) (*paging.Cursor, bool) {
+	cursor, reason := parseCursor(r, offset)
+	if reason == "" && cursor != nil && !bids.ValidCursor(cursor) {
+		reason = "the cursor does not match the sort order"
+	}
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
+		return nil, false
+	}
+	return cursor, true
+}
+
+// sendBidList writes a listing fetched with one extra row and sets
+// X-Next-Cursor when another page follows.
+func sendBidList(w http.ResponseWriter, list []*bids.Bid, limit int32) error {
+	list, more := paging.Trim(list, limit)
+	if more {
+		w.Header().Set("X-Next-Cursor", bids.CursorAfter(list[len(list)-1]).Encode())
+	}
+	return json.NewEncoder(w).Encode(v1.NewBids(list))
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"avitointern/pkg/database"
+
+	"go.uber.org/zap"
+)
+
+const healthTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	SQL    database.Database
+	Logger *zap.SugaredLogger
+}
+
+type HealthResponse struct {
+	Status string         `json:"status"`
+	Pool   database.Stats `json:"pool"`
+}
+
+// Health pings the database and reports the pool state; 503 when the ping fails.
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	resp := HealthResponse{Status: "ok", Pool: h.SQL.Stats()}
+	status := http.StatusOK
+	if err := h.SQL.Health(ctx); err != nil {
+		h.Logger.Errorf("health check failed: %v", err)
+		resp.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.Logger.Errorf("json encoding error: %v", err)
+	}
+}
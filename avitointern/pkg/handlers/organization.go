@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	v1 "avitointern/pkg/api/v1"
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/authz"
+	"avitointern/pkg/database"
+	"avitointern/pkg/organizations"
+	"avitointern/pkg/session"
+	"avitointern/pkg/user"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type OrganizationsHandler struct {
+	SQL    database.Database
+	Users  user.UserRepo
+	Authz  *authz.Policy
+	Logger *zap.SugaredLogger
+}
+
+type OrganizationProfile struct {
+	*organizations.Organization
+	Responsibles []string `json:"responsibles"`
+}
+
+type organizationRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Type        *string `json:"type"`
+	INN         *string `json:"inn"`
+	OGRN        *string `json:"ogrn"`
+	KPP         *string `json:"kpp"`
+}
+
+// apply copies the set fields onto org and validates the result; a new
+// organization must carry all identifiers required for its type.
+func (req *organizationRequest) apply(org *organizations.Organization, isNew bool) string {
+	if req.Name != nil {
+		org.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		org.Description = *req.Description
+	}
+	if req.Type != nil {
+		if !organizations.ValidType(*req.Type) {
+			return "invalid format type"
+		}
+		org.Type = organizations.Type(*req.Type)
+	}
+	if req.INN != nil {
+		org.INN = strings.TrimSpace(*req.INN)
+	}
+	if req.OGRN != nil {
+		org.OGRN = strings.TrimSpace(*req.OGRN)
+	}
+	if req.KPP != nil {
+		org.KPP = strings.ToUpper(strings.TrimSpace(*req.KPP))
+	}
+
+	if org.Name == "" || utf8.RuneCountInString(org.Name) > organizations.MaxNameLength {
+		return "invalid format name"
+	}
+	if utf8.RuneCountInString(org.Description) > organizations.MaxDescriptionLength {
+		return "invalid format description"
+	}
+	if err := organizations.ValidateIdentifiers(org, isNew); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// New creates an organization; the caller becomes its first responsible.
+func (h *OrganizationsHandler) New(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	var createRequest organizationRequest
+	if err = json.NewDecoder(r.Body).Decode(&createRequest); err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if createRequest.Name == nil || createRequest.Type == nil {
+		h.errSend(w, r, "missing required fields", http.StatusBadRequest)
+		return
+	}
+
+	org := new(organizations.Organization)
+	if reason := createRequest.apply(org, true); reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
+		return
+	}
+
+	err = h.SQL.InsertOrganization(r.Context(), org, sess.User.ID)
+	if err != nil {
+		h.fail(w, r, fmt.Errorf("insert organization: %w", err))
+		return
+	}
+
+	h.sendProfile(w, r, org, http.StatusCreated)
+	h.Logger.Infof("Organization %v created by %v", org.ID, sess.User.Username)
+}
+
+func (h *OrganizationsHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	org, ok := h.organizationFromVars(w, r)
+	if !ok {
+		return
+	}
+	h.sendProfile(w, r, org, http.StatusOK)
+}
+
+func (h *OrganizationsHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	var updateRequest organizationRequest
+	if err = json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	org, ok := h.organizationFromVars(w, r)
+	if !ok {
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.OrgEdit, authz.Resource{OrganizationID: org.ID}) {
+		return
+	}
+
+	if reason := updateRequest.apply(org, false); reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
+		return
+	}
+
+	org, err = h.SQL.UpdateOrganization(r.Context(), org)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.sendProfile(w, r, org, http.StatusOK)
+	h.Logger.Infof("Organization %v edited by %v", org.ID, sess.User.Username)
+}
+
+func (h *OrganizationsHandler) Tenders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, reason := parsePage(r)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
+		return
+	}
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	org, ok := h.organizationFromVars(w, r)
+	if !ok {
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.TenderView, authz.Resource{OrganizationID: org.ID}) {
+		return
+	}
+
+	tendersList, err := h.SQL.OrganizationTenders(r.Context(), org.ID, limit, offset)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewTenders(tendersList)); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+}
+
+func (h *OrganizationsHandler) AddResponsible(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, org, member, ok := h.responsibleFromVars(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Users.AddResponsible(r.Context(), org.ID, member.ID); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.Logger.Infof("%v made %v responsible for organization %v", sess.User.Username, member.Username, org.ID)
+}
+
+// RemoveResponsible refuses to remove the last responsible, which would leave
+// the organization without anyone to manage it.
+func (h *OrganizationsHandler) RemoveResponsible(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, org, member, ok := h.responsibleFromVars(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Users.RemoveResponsible(r.Context(), org.ID, member.ID); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.Logger.Infof("%v removed %v from responsibles of organization %v", sess.User.Username, member.Username, org.ID)
+}
+
+// responsibleFromVars resolves the organization and the user from the route
+// and checks that the caller may manage the organization's responsibles.
+func (h *OrganizationsHandler) responsibleFromVars(w http.ResponseWriter, r *http.Request) (*session.Session,
+	*organizations.Organization, *user.User, bool) {
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return nil, nil, nil, false
+	}
+
+	org, ok := h.organizationFromVars(w, r)
+	if !ok {
+		return nil, nil, nil, false
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.OrgResponsibles, authz.Resource{OrganizationID: org.ID}) {
+		return nil, nil, nil, false
+	}
+
+	member, err := h.Users.GetUserByUsername(r.Context(), mux.Vars(r)["username"])
+	if err != nil {
+		h.fail(w, r, err)
+		return nil, nil, nil, false
+	}
+	return sess, org, member, true
+}
+
+func (h *OrganizationsHandler) organizationFromVars(w http.ResponseWriter, r *http.Request) (*organizations.Organization, bool) {
+	org, err := h.SQL.GetOrganizationByID(r.Context(), mux.Vars(r)["organizationID"])
+	if err != nil {
+		h.fail(w, r, err)
+		return nil, false
+	}
+	return org, true
+}
+
+func (h *OrganizationsHandler) sendProfile(w http.ResponseWriter, r *http.Request, org *organizations.Organization, status int) {
+	responsibles, err := h.Users.ListResponsibles(r.Context(), org.ID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	profile := OrganizationProfile{Organization: org, Responsibles: make([]string, 0, len(responsibles))}
+	for _, u := range responsibles {
+		profile.Responsibles = append(profile.Responsibles, u.Username)
+	}
+	w.WriteHeader(status)
+	if err = json.NewEncoder(w).Encode(profile); err != nil {
+		h.fail(w, r, err)
+	}
+}
+
+func (h *OrganizationsHandler) errSend(w http.ResponseWriter, r *http.Request, reason string, status int) {
+	sendError(w, r, h.Logger, reason, status)
+}
+
+func (h *OrganizationsHandler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	fail(w, r, h.Logger, err)
+}
@@ -1,16 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"html/template"
 	"net/http"
 	"strconv"
 	"time"
+	"unicode/utf8"
 
+	v1 "avitointern/pkg/api/v1"
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/authz"
 	"avitointern/pkg/database"
+	"avitointern/pkg/paging"
 	"avitointern/pkg/session"
 	"avitointern/pkg/tenders"
+	"avitointern/pkg/user"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -18,78 +25,52 @@ import (
 )
 
 type TendersHandler struct {
-	SQL         database.Database
-	Tmpl        *template.Template
-	TendersRepo tenders.TendersRepo
-	Logger      *zap.SugaredLogger
-}
-
-type TenderResponse struct {
-	TenderID          string `json:"id"`
-	TenderName        string `json:"name"`
-	TenderDescription string `json:"description"`
-	Status            string `json:"status"`
-	ServiceType       string `json:"serviceType"`
-	Version           int32  `json:"version"`
-	CreatedAt         string `json:"createdAt"` // RFC3339 format.
+	SQL    database.Database
+	Authz  *authz.Policy
+	States *tenders.StateMachine
+	Tmpl   *template.Template
+	Logger *zap.SugaredLogger
 }
 
 func (h *TendersHandler) Tenders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	limit, err := parseInt32(r, "limit", 5)
-	if err != nil {
-		h.errSend(w, "bad query", http.StatusBadRequest)
+	limit, offset, reason := parsePage(r)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
 		return
 	}
 
-	offset, err := parseInt32(r, "offset", 0)
-	if err != nil {
-		h.errSend(w, "bad query", http.StatusBadRequest)
+	filter, reason := parseTenderFilter(r, offset)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
 		return
 	}
 
-	var serviceType []tenders.ServiceType
-	if serviceStr := r.URL.Query()["service_type"]; len(serviceType) != 0 {
-		for _, service := range serviceStr {
-			serviceType = append(serviceType, tenders.ServiceType(service))
-		}
-	}
-
-	tenders, err := h.SQL.GetQuery(limit, offset, serviceType)
+	sess, err := session.SessionFromContext(r.Context())
 	if err != nil {
-		h.errSend(w, "db err", http.StatusInternalServerError)
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
 
-	for _, elem := range tenders {
-		fmt.Println(elem)
-	}
-
-	err = json.NewEncoder(w).Encode(tenders)
+	tenders, err := h.SQL.GetQuery(r.Context(), viewerOf(sess), paging.Lookahead(limit), offset, filter)
 	if err != nil {
-		h.errSend(w, "json encoding error", http.StatusInternalServerError)
+		h.fail(w, r, err)
 		return
 	}
+
+	h.sendTenderList(w, r, viewerOf(sess), filter, limit, tenders)
 }
 
 func (h *TendersHandler) New(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	sess, err := session.SessionFromContext(r.Context())
-	if sess.User.OrganizationID == "" {
-		h.errSend(w, "user does not have an organization", http.StatusForbidden)
-		return
-	}
 	if err != nil {
-		h.errSend(w, "err with sess", http.StatusBadRequest)
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
 
-	if err = r.ParseForm(); err != nil {
-		h.errSend(w, "err with parseform", http.StatusBadRequest)
-		return
-	}
 	var updateRequest struct {
 		Name           *string              `json:"name"`
 		Description    *string              `json:"description"`
@@ -98,12 +79,20 @@ func (h *TendersHandler) New(w http.ResponseWriter, r *http.Request) {
 		Author         *string              `json:"creatorUsername"`
 	}
 	if err = json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
-		h.errSend(w, "invalid request body", http.StatusBadRequest)
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
 		return
 	}
-	if updateRequest.Name == nil || updateRequest.Description == nil ||
+	if updateRequest.Name == nil || updateRequest.Description == nil || updateRequest.ServiceType == nil ||
 		updateRequest.OrganizationID == nil || updateRequest.Author == nil {
-		h.errSend(w, "bad json parse", http.StatusUnauthorized)
+		h.errSend(w, r, "missing required fields", http.StatusBadRequest)
+		return
+	}
+	org, err := h.SQL.GetOrganizationByID(r.Context(), *updateRequest.OrganizationID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.TenderCreate, authz.Resource{OrganizationID: org.ID}) {
 		return
 	}
 
@@ -113,7 +102,7 @@ func (h *TendersHandler) New(w http.ResponseWriter, r *http.Request) {
 	tender.TenderDescription = *updateRequest.Description
 	tender.ServiceType = *updateRequest.ServiceType
 	tender.Status = tenders.Created
-	tender.OrganizationID = *updateRequest.OrganizationID
+	tender.OrganizationID = org.ID
 	tender.Version = 1
 	tender.CreatedAt = time.Now().Format(time.RFC3339) // RFC3339 format.
 	tender.Author = sess.User.Username
@@ -125,30 +114,32 @@ func (h *TendersHandler) New(w http.ResponseWriter, r *http.Request) {
 		ServiceType:       string(tender.ServiceType),
 		Version:           1,
 		Status:            tender.Status,
+		ModifiedBy:        tender.Author,
+		ModifiedAt:        tender.CreatedAt,
 	}
 
-	lastID, err := h.SQL.InsertTender(tender)
+	lastID, err := h.SQL.InsertTender(r.Context(), tender)
 	if err != nil {
-		h.errSend(w, "sql DB err", http.StatusInternalServerError)
+		h.fail(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	h.Logger.Infof("Insert with id LastInsertId: %v", lastID)
+	h.sendTender(w, r, tender)
 }
 
 func (h *TendersHandler) My(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	limit, err := parseInt32(r, "limit", 5)
-	if err != nil {
-		h.errSend(w, "bad query in limit", http.StatusBadRequest)
+	limit, offset, reason := parsePage(r)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
 		return
 	}
 
-	offset, err := parseInt32(r, "offset", 0)
-	if err != nil {
-		h.errSend(w, "bad query in offset", http.StatusBadRequest)
+	filter, reason := parseTenderFilter(r, offset)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
 		return
 	}
 
@@ -156,24 +147,45 @@ func (h *TendersHandler) My(w http.ResponseWriter, r *http.Request) {
 
 	sess, err := session.SessionFromContext(r.Context())
 	if err != nil {
-		h.errSend(w, "session err", http.StatusInternalServerError)
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
 	if username != sess.User.Username {
-		h.errSend(w, "session and username err", http.StatusInternalServerError)
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
 
-	tenders, err := h.SQL.My(limit, offset, sess.User.Username)
+	filter.Author = sess.User.Username
+	tenders, err := h.SQL.My(r.Context(), viewerOf(sess), paging.Lookahead(limit), offset, sess.User.Username, filter)
 	if err != nil {
-		h.errSend(w, "db err", http.StatusInternalServerError)
+		h.fail(w, r, err)
 		return
 	}
 
-	err = json.NewEncoder(w).Encode(tenders)
-	if err != nil {
-		h.errSend(w, "json encoding error", http.StatusInternalServerError)
-		return
+	h.sendTenderList(w, r, viewerOf(sess), filter, limit, tenders)
+}
+
+// sendTenderList writes a listing fetched with one extra row. It sets
+// X-Next-Cursor when another page follows and, with ?withTotal=true,
+// X-Total-Count.
+func (h *TendersHandler) sendTenderList(w http.ResponseWriter, r *http.Request, viewer tenders.Viewer,
+	filter tenders.Filter, limit int32, list []*tenders.Tender) {
+	list, more := paging.Trim(list, limit)
+	if more {
+		w.Header().Set("X-Next-Cursor", filter.CursorAfter(list[len(list)-1]).Encode())
+	}
+
+	if r.URL.Query().Get("withTotal") == "true" {
+		total, err := h.SQL.CountTenders(r.Context(), viewer, filter)
+		if err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	}
+
+	if err := json.NewEncoder(w).Encode(v1.NewTenders(list)); err != nil {
+		h.fail(w, r, err)
 	}
 }
 
@@ -182,93 +194,96 @@ func (h *TendersHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 
 	username := r.URL.Query().Get("username")
 	sess, err := session.SessionFromContext(r.Context())
-	if username != sess.User.Username {
-		h.errSend(w, "user Unauthorized", http.StatusUnauthorized)
+	if err != nil || username != sess.User.Username {
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
-	if err != nil {
-		h.errSend(w, "session err", http.StatusBadRequest)
+
+	elem, ok := h.visibleTender(w, r, sess)
+	if !ok {
 		return
 	}
 
-	vars := mux.Vars(r)
-	id := vars["tenderID"]
-	elem, err := h.SQL.GetTenderByID(id)
+	w.Header().Set("ETag", tenders.ETag(elem.Version))
+	err = json.NewEncoder(w).Encode(elem.Status)
 	if err != nil {
-		h.errSend(w, "err with GetTenderByID", http.StatusBadRequest)
-		return
-	}
-	if elem == nil {
-		h.errSend(w, "the tender was not found", http.StatusNotFound)
-		return
-	}
-	if elem.Author != username {
-		h.errSend(w, "there are not enough permissions to perform the action", http.StatusForbidden)
+		h.fail(w, r, err)
 		return
 	}
 
-	err = json.NewEncoder(w).Encode(elem.Status)
+	h.Logger.Infof("Status by ID: %v", elem.Status)
+}
+
+// Get returns the tender if it is visible to the session user.
+func (h *TendersHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
 	if err != nil {
-		h.errSend(w, "bad json encode", http.StatusBadRequest)
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	h.Logger.Infof("Status by ID: %v", elem.Status)
+	elem, ok := h.visibleTender(w, r, sess)
+	if !ok {
+		return
+	}
+	h.sendTender(w, r, elem)
 }
 
 func (h *TendersHandler) EditStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	status := r.URL.Query().Get("status")
-	if status == "" || !ContainsString([]string{"Created", "Published", "Closed"}, status) {
-		h.errSend(w, "invalid format status", http.StatusBadRequest)
+	if !tenders.ValidStatus(status) {
+		h.errSend(w, r, "invalid format status", http.StatusBadRequest)
 		return
 	}
 
 	username := r.URL.Query().Get("username")
 	if username == "" {
-		h.errSend(w, "invalid format username", http.StatusBadRequest)
+		h.errSend(w, r, "invalid format username", http.StatusBadRequest)
 		return
 	}
 	sess, err := session.SessionFromContext(r.Context())
-	if username != sess.User.Username {
-		h.errSend(w, "user Unauthorized", http.StatusUnauthorized)
+	if err != nil || username != sess.User.Username {
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
+
+	precondition, err := ifMatch(r)
 	if err != nil {
-		h.errSend(w, "sess err", http.StatusBadRequest)
+		h.errSend(w, r, "invalid If-Match header", http.StatusBadRequest)
 		return
 	}
 
 	vars := mux.Vars(r)
 	tenderID := vars["tenderID"]
-	elem, err := h.SQL.UpdateTenderStatus(tenderID, tenders.Status(status))
-	if elem == nil {
-		h.errSend(w, "the tender was not found", http.StatusNotFound)
+	tender, ok := h.managedTender(w, r, sess, tenderID, authz.TenderPublish)
+	if !ok {
 		return
 	}
-	if err != nil {
-		h.errSend(w, "bad json encode", http.StatusBadRequest)
+	if !precondition.Matches(tender.Version) {
+		h.mutationErr(w, r, database.ErrVersionConflict, http.StatusPreconditionFailed)
 		return
 	}
 
-	tender := TenderResponse{
-		TenderID:          elem.TenderID,
-		TenderName:        elem.TenderName,
-		TenderDescription: elem.TenderDescription,
-		Status:            string(elem.Status),
-		ServiceType:       string(elem.ServiceType),
-		Version:           elem.Version,
-		CreatedAt:         elem.CreatedAt,
+	// The transition is checked against the revision that was read, so it is
+	// only stored on top of that revision.
+	conflictStatus := http.StatusConflict
+	if precondition.Present() {
+		conflictStatus = http.StatusPreconditionFailed
 	}
-
-	if err := json.NewEncoder(w).Encode(tender); err != nil {
-		h.errSend(w, "error encoding JSON", http.StatusInternalServerError)
+	tr := tenders.Transition{Tender: tender, To: tenders.Status(status), Actor: sess.User.Username}
+	elem, err := h.States.Apply(r.Context(), tr, func() (*tenders.Tender, error) {
+		return h.SQL.UpdateTenderStatus(r.Context(), tenderID, tr.To, tr.Actor, tender.Version)
+	})
+	if err != nil {
+		h.mutationErr(w, r, err, conflictStatus)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	h.sendTender(w, r, elem)
 	h.Logger.Infof("Edit status by ID: %v", elem.Status)
 }
 
@@ -277,16 +292,12 @@ func (h *TendersHandler) Edit(w http.ResponseWriter, r *http.Request) {
 
 	username := r.URL.Query().Get("username")
 	if username == "" {
-		h.errSend(w, "invalid format username", http.StatusBadRequest)
+		h.errSend(w, r, "invalid format username", http.StatusBadRequest)
 		return
 	}
 	sess, err := session.SessionFromContext(r.Context())
-	if username != sess.User.Username {
-		h.errSend(w, "user Unauthorized", http.StatusUnauthorized)
-		return
-	}
-	if err != nil {
-		h.errSend(w, "sess err", http.StatusBadRequest)
+	if err != nil || username != sess.User.Username {
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
 
@@ -296,25 +307,28 @@ func (h *TendersHandler) Edit(w http.ResponseWriter, r *http.Request) {
 		ServiceType *string `json:"serviceType"`
 	}
 	if err = json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
-		h.errSend(w, "invalid request body", http.StatusBadRequest)
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	precondition, err := ifMatch(r)
+	if err != nil {
+		h.errSend(w, r, "invalid If-Match header", http.StatusBadRequest)
 		return
 	}
 
 	vars := mux.Vars(r)
 	tenderID := vars["tenderID"]
-	elem, err := h.SQL.GetTenderByID(tenderID)
-	if elem == nil {
-		h.errSend(w, "the tender was not found", http.StatusNotFound)
+	elem, err := h.SQL.GetTenderByID(r.Context(), tenderID)
+	if err != nil {
+		h.fail(w, r, err)
 		return
 	}
-	if err != nil {
-		h.errSend(w, "err with GetTenderByID", http.StatusBadRequest)
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.TenderEdit, tenderResource(elem)) {
 		return
 	}
-
-	err = json.NewEncoder(w).Encode(elem.Status)
-	if err != nil {
-		h.errSend(w, "bad json encode", http.StatusBadRequest)
+	if !precondition.Matches(elem.Version) {
+		h.mutationErr(w, r, database.ErrVersionConflict, http.StatusPreconditionFailed)
 		return
 	}
 
@@ -328,20 +342,22 @@ func (h *TendersHandler) Edit(w http.ResponseWriter, r *http.Request) {
 		elem.ServiceType = tenders.ServiceType(*updateRequest.ServiceType)
 	}
 
-	var tender *tenders.Tender
+	// The new values are computed from elem, so the edit is only applied on top
+	// of the version that was read. Without If-Match a concurrent change is a 409.
 	if updateRequest.Name != nil || updateRequest.Description != nil || updateRequest.ServiceType != nil {
-		tender, err = h.SQL.EditTender(elem.TenderID, elem.TenderName, elem.TenderDescription, elem.ServiceType)
+		conflictStatus := http.StatusConflict
+		if precondition.Present() {
+			conflictStatus = http.StatusPreconditionFailed
+		}
+		elem, err = h.SQL.EditTender(r.Context(), elem.TenderID, elem.TenderName, elem.TenderDescription, elem.ServiceType,
+			sess.User.Username, elem.Version)
 		if err != nil {
-			h.errSend(w, "my tender err", http.StatusInternalServerError)
+			h.mutationErr(w, r, err, conflictStatus)
 			return
 		}
 	}
-	if err := json.NewEncoder(w).Encode(tender); err != nil {
-		h.errSend(w, "error encoding JSON", http.StatusInternalServerError)
-		return
-	}
 
-	w.WriteHeader(http.StatusOK)
+	h.sendTender(w, r, elem)
 	h.Logger.Infof("EditTender PUT status by ID: %v", elem.Status)
 }
 
@@ -350,68 +366,351 @@ func (h *TendersHandler) Rollback(w http.ResponseWriter, r *http.Request) {
 
 	username := r.URL.Query().Get("username")
 	if username == "" {
-		h.errSend(w, "invalid format username", http.StatusBadRequest)
+		h.errSend(w, r, "invalid format username", http.StatusBadRequest)
 		return
 	}
 	sess, err := session.SessionFromContext(r.Context())
-	if username != sess.User.Username {
-		h.errSend(w, "user Unauthorized", http.StatusUnauthorized)
+	if err != nil || username != sess.User.Username {
+		h.fail(w, r, apperr.ErrUnauthorized)
 		return
 	}
+
+	vars := mux.Vars(r)
+	version, err := strconv.ParseInt(vars["version"], 10, 32)
+	if err != nil || version < 1 {
+		h.errSend(w, r, "bad parse version", http.StatusBadRequest)
+		return
+	}
+
+	precondition, err := ifMatch(r)
 	if err != nil {
-		h.errSend(w, "sess err", http.StatusBadRequest)
+		h.errSend(w, r, "invalid If-Match header", http.StatusBadRequest)
 		return
 	}
 
-	vars := mux.Vars(r)
-	tenderID := vars["tenderID"]
-	elem, err := h.SQL.GetTenderByID(tenderID)
-	if elem == nil {
-		h.errSend(w, "the tender was not found", http.StatusNotFound)
+	tender, ok := h.managedTender(w, r, sess, vars["tenderID"], authz.TenderEdit)
+	if !ok {
 		return
 	}
-	if err != nil {
-		h.errSend(w, "err with GetTenderByID", http.StatusBadRequest)
+	if !precondition.Matches(tender.Version) {
+		h.mutationErr(w, r, database.ErrVersionConflict, http.StatusPreconditionFailed)
 		return
 	}
 
-	err = json.NewEncoder(w).Encode(elem.Status)
+	// As with an edit, a tender that changes after the check is a 412 too.
+	var ifVersion int32
+	if precondition.Present() {
+		ifVersion = tender.Version
+	}
+	elem, err := h.SQL.Rollback(r.Context(), vars["tenderID"], int32(version), sess.User.Username, ifVersion)
 	if err != nil {
-		h.errSend(w, "bad json encode", http.StatusBadRequest)
+		h.mutationErr(w, r, err, http.StatusPreconditionFailed)
 		return
 	}
 
-	versionStr := vars["version"]
-	version, err := strconv.ParseInt(versionStr, 10, 32)
-	if err != nil {
-		h.errSend(w, "bad parse version", http.StatusNotFound)
+	h.sendTender(w, r, elem)
+
+	h.Logger.Infof("Rollback tender %v to version %v by %v, new version %v",
+		elem.TenderID, version, sess.User.Username, elem.Version)
+}
+
+func (h *TendersHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, reason := parsePage(r)
+	if reason != "" {
+		h.errSend(w, r, reason, http.StatusBadRequest)
 		return
 	}
 
-	tender, err := h.SQL.Rollback(tenderID, int32(version))
+	tender, ok := h.tenderForHistory(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.SQL.GetTenderVersions(r.Context(), tender.TenderID, limit, offset)
 	if err != nil {
-		h.errSend(w, "bad sql request wherer", http.StatusNotFound)
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewTenderVersions(versions)); err != nil {
+		h.fail(w, r, err)
 		return
 	}
+}
 
-	fmt.Println(tender)
+func (h *TendersHandler) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	if err := json.NewEncoder(w).Encode(tender); err != nil {
-		h.errSend(w, "error encoding JSON", http.StatusInternalServerError)
+	version, err := strconv.ParseInt(mux.Vars(r)["version"], 10, 32)
+	if err != nil || version < 1 {
+		h.errSend(w, r, "bad parse version", http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	h.Logger.Infof("EditTender PUT status by ID: %v", elem.Status)
+	tender, ok := h.tenderForHistory(w, r)
+	if !ok {
+		return
+	}
+
+	ver, ok := h.tenderVersion(w, r, tender.TenderID, int32(version))
+	if !ok {
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(v1.NewTenderVersion(ver)); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+}
+
+func (h *TendersHandler) VersionsDiff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, err := parseInt32(r, "from", 0)
+	if err != nil || from < 1 {
+		h.errSend(w, r, "bad query in from", http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseInt32(r, "to", 0)
+	if err != nil || to < 1 {
+		h.errSend(w, r, "bad query in to", http.StatusBadRequest)
+		return
+	}
+
+	tender, ok := h.tenderForHistory(w, r)
+	if !ok {
+		return
+	}
+
+	fromVer, ok := h.tenderVersion(w, r, tender.TenderID, from)
+	if !ok {
+		return
+	}
+	toVer, ok := h.tenderVersion(w, r, tender.TenderID, to)
+	if !ok {
+		return
+	}
+
+	diff := struct {
+		TenderID string                `json:"tenderId"`
+		From     int32                 `json:"from"`
+		To       int32                 `json:"to"`
+		Changes  []tenders.FieldChange `json:"changes"`
+	}{
+		TenderID: tender.TenderID,
+		From:     from,
+		To:       to,
+		Changes:  tenders.Diff(fromVer, toVer),
+	}
+
+	if err = json.NewEncoder(w).Encode(diff); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+}
+
+// tenderForHistory loads the tender from the route and checks that the caller
+// may read its history.
+func (h *TendersHandler) tenderForHistory(w http.ResponseWriter, r *http.Request) (*tenders.Tender, bool) {
+	username := r.URL.Query().Get("username")
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil || username != sess.User.Username {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return nil, false
+	}
+
+	tender, err := h.SQL.GetTenderByID(r.Context(), mux.Vars(r)["tenderID"])
+	if err != nil {
+		h.fail(w, r, err)
+		return nil, false
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, authz.TenderHistory, tenderResource(tender)) {
+		return nil, false
+	}
+	return tender, true
+}
+
+// visibleTender loads the tender from the route; hidden tenders are reported
+// as not found so that their existence does not leak.
+func (h *TendersHandler) visibleTender(w http.ResponseWriter, r *http.Request, sess *session.Session) (*tenders.Tender, bool) {
+	tender, err := h.SQL.GetVisibleTender(r.Context(), mux.Vars(r)["tenderID"], viewerOf(sess))
+	if err != nil {
+		h.fail(w, r, err)
+		return nil, false
+	}
+	return tender, true
+}
+
+func viewerOf(sess *session.Session) tenders.Viewer {
+	return tenders.Viewer{Username: sess.User.Username, Admin: sess.User.IsAdmin}
+}
+
+// managedTender loads the tender and checks that the session user holds perm on it.
+func (h *TendersHandler) managedTender(w http.ResponseWriter, r *http.Request, sess *session.Session,
+	tenderID string, perm authz.Permission) (*tenders.Tender, bool) {
+	tender, err := h.SQL.GetTenderByID(r.Context(), tenderID)
+	if err != nil {
+		h.fail(w, r, err)
+		return nil, false
+	}
+	if !authorize(w, r, h.Authz, h.Logger, sess, perm, tenderResource(tender)) {
+		return nil, false
+	}
+	return tender, true
+}
+
+func (h *TendersHandler) tenderVersion(w http.ResponseWriter, r *http.Request, tenderID string, version int32) (*tenders.TenderVer, bool) {
+	ver, err := h.SQL.GetTenderVersion(r.Context(), tenderID, version)
+	if err != nil {
+		h.fail(w, r, err)
+		return nil, false
+	}
+	if ver == nil {
+		h.fail(w, r, database.ErrVersionNotFound.WithDetailf("version %d was not found", version))
+		return nil, false
+	}
+	return ver, true
+}
+
+func (h *TendersHandler) sendTender(w http.ResponseWriter, r *http.Request, elem *tenders.Tender) {
+	w.Header().Set("ETag", tenders.ETag(elem.Version))
+	if err := json.NewEncoder(w).Encode(v1.NewTender(elem)); err != nil {
+		h.fail(w, r, err)
+	}
+}
+
+// mutationErr answers a failed tender update; conflictStatus is 412 when the
+// client sent If-Match and 409 when the conflict was detected on our side.
+func (h *TendersHandler) mutationErr(w http.ResponseWriter, r *http.Request, err error, conflictStatus int) {
+	if conflictStatus == http.StatusPreconditionFailed && errors.Is(err, database.ErrVersionConflict) {
+		err = apperr.ErrPreconditionFailed.WithDetail(err.Error())
+	}
+	h.fail(w, r, err)
+}
+
+// NewTenderStates returns the tender state machine with the guards and hooks
+// the API relies on: a tender is only published by its own organization, as
+// authz.TenderPublish has it, and every transition is logged. The guard holds
+// for the storage too, which checks transitions on its own.
+func NewTenderStates(users user.UserRepo, logger *zap.SugaredLogger) *tenders.StateMachine {
+	policy := authz.NewPolicy(users, logger)
+	states := tenders.NewStateMachine()
+	states.Guard(tenders.Published, tenders.OwnedBy(func(ctx context.Context, username, organizationID string) (bool, error) {
+		u, err := users.GetUserByUsername(ctx, username)
+		if errors.Is(err, user.ErrNoUser) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return policy.Allowed(ctx, u, authz.TenderPublish, authz.Resource{OrganizationID: organizationID})
+	}))
+	states.OnTransition(func(_ context.Context, tr tenders.Transition, updated *tenders.Tender) {
+		logger.Infow("tender status changed",
+			"tender", updated.TenderID,
+			"from", tr.Tender.Status,
+			"to", updated.Status,
+			"actor", tr.Actor,
+			"version", updated.Version,
+		)
+	})
+	return states
+}
+
+// ifMatch reads the If-Match header. A tender that does not match it is a 412.
+func ifMatch(r *http.Request) (tenders.IfMatch, error) {
+	return tenders.ParseIfMatch(r.Header.Get("If-Match"))
 }
 
-func ContainsString(slice []string, value string) bool {
-	for _, v := range slice {
-		if v == value {
-			return true
+// parseTenderFilter reads the listing filters, sort order and cursor from the
+// query string; the reason is non-empty when a parameter is invalid.
+func parseTenderFilter(r *http.Request, offset int32) (tenders.Filter, string) {
+	query := r.URL.Query()
+	var filter tenders.Filter
+
+	for _, service := range query["service_type"] {
+		if !tenders.ValidServiceType(service) {
+			return filter, "invalid format service_type"
+		}
+		filter.ServiceTypes = append(filter.ServiceTypes, tenders.ServiceType(service))
+	}
+	for _, status := range query["status"] {
+		if !tenders.ValidStatus(status) {
+			return filter, "invalid format status"
+		}
+		filter.Statuses = append(filter.Statuses, tenders.Status(status))
+	}
+
+	filter.OrganizationID = query.Get("organizationId")
+	if len(filter.OrganizationID) > 100 {
+		return filter, "invalid format organizationId"
+	}
+	filter.Author = query.Get("author")
+	if len(filter.Author) > 50 {
+		return filter, "invalid format author"
+	}
+	filter.NamePrefix = query.Get("name")
+	if utf8.RuneCountInString(filter.NamePrefix) > 100 {
+		return filter, "invalid format name"
+	}
+
+	var err error
+	if from := query.Get("createdFrom"); from != "" {
+		if filter.CreatedFrom, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, "invalid format createdFrom"
+		}
+	}
+	if to := query.Get("createdTo"); to != "" {
+		if filter.CreatedTo, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, "invalid format createdTo"
 		}
 	}
-	return false
+
+	filter.Sort = tenders.SortName
+	if sortBy := query.Get("sort"); sortBy != "" {
+		if !tenders.ValidSort(sortBy) {
+			return filter, "invalid format sort"
+		}
+		filter.Sort = tenders.SortField(sortBy)
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return filter, "invalid format order"
+	}
+
+	cursor, reason := parseCursor(r, offset)
+	if reason != "" {
+		return filter, reason
+	}
+	if cursor != nil {
+		if err = filter.SetCursor(cursor); err != nil {
+			return filter, "the cursor does not match the sort order"
+		}
+	}
+
+	return filter, ""
+}
+
+// parseCursor decodes the cursor query parameter, which replaces offset.
+func parseCursor(r *http.Request, offset int32) (*paging.Cursor, string) {
+	raw := r.URL.Query().Get("cursor")
+	if raw == "" {
+		return nil, ""
+	}
+	if offset != 0 {
+		return nil, "cursor and offset cannot be combined"
+	}
+	cursor, err := paging.Decode(raw)
+	if err != nil {
+		return nil, "invalid format cursor"
+	}
+	return cursor, ""
 }
 
 func parseInt32(r *http.Request, param string, defaultVal int32) (int32, error) {
@@ -426,14 +725,42 @@ func parseInt32(r *http.Request, param string, defaultVal int32) (int32, error)
 	return defaultVal, nil
 }
 
-func (h *TendersHandler) errSend(w http.ResponseWriter, reason string, status int) {
-	var errorResponse struct {
-		Reason string `json:"reason"`
+// parsePage reads limit and offset for a listing. The spec bounds them, but
+// not every listing is in the spec, so they are checked here for all of them;
+// the reason is non-empty when one is out of range.
+func parsePage(r *http.Request) (limit, offset int32, reason string) {
+	limit, err := parseInt32(r, "limit", paging.DefaultLimit)
+	if err != nil || limit < 0 || limit > paging.MaxLimit {
+		return 0, 0, "bad query in limit"
+	}
+	offset, err = parseInt32(r, "offset", 0)
+	if err != nil || offset < 0 {
+		return 0, 0, "bad query in offset"
 	}
-	w.WriteHeader(status)
-	errorResponse.Reason = reason
-	err := json.NewEncoder(w).Encode(errorResponse)
+	return limit, offset, ""
+}
+
+func (h *TendersHandler) errSend(w http.ResponseWriter, r *http.Request, reason string, status int) {
+	sendError(w, r, h.Logger, reason, status)
+}
+
+func (h *TendersHandler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	fail(w, r, h.Logger, err)
+}
+
+// sendError answers with the generic problem for the status.
+func sendError(w http.ResponseWriter, r *http.Request, logger *zap.SugaredLogger, reason string, status int) {
+	err := apperr.NewProblem(r, apperr.FromStatus(status, reason)).Write(w)
 	if err != nil {
-		h.Logger.Infof("err in h.errSend with encode")
+		logger.Infof("err in h.errSend with encode")
+	}
+}
+
+// fail translates a domain error into its status and problem body. Errors
+// that are not apperr.Error become 500 and are logged with the request ID.
+func fail(w http.ResponseWriter, r *http.Request, logger *zap.SugaredLogger, err error) {
+	p := apperr.Write(w, r, err)
+	if p.Status >= http.StatusInternalServerError {
+		logger.Errorw("request failed", "request_id", p.RequestID, "error", err)
 	}
 }
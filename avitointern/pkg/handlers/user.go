@@ -1,15 +1,25 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"log"
+	"mime"
 	"net/http"
 	"time"
 
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/i18n"
+	"avitointern/pkg/mail"
 	"avitointern/pkg/session"
+	"avitointern/pkg/token"
 	"avitointern/pkg/user"
 
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"golang.org/x/text/language"
 )
 
 type UserHandler struct {
@@ -17,16 +27,23 @@ type UserHandler struct {
 	Logger   *zap.SugaredLogger
 	UserRepo user.UserRepo
 	Sessions *session.SessionsManager
+	Tokens   *token.Manager
+	Mailer   mail.Mailer
+	// ExposeResetTokens returns the reset token in the response, for local
+	// development without mail. Never enable it in production.
+	ExposeResetTokens bool
 }
 
 func (h *UserHandler) Index(w http.ResponseWriter, r *http.Request) {
 	_, err := session.SessionFromContext(r.Context())
 	if err == nil {
-		http.Redirect(w, r, "/tenders", http.StatusFound)
+		http.Redirect(w, r, "/api/v1/tenders", http.StatusFound)
 		return
 	}
 
-	err = h.Tmpl.ExecuteTemplate(w, "login.html", nil)
+	err = h.Tmpl.ExecuteTemplate(w, "login.html", struct {
+		Lang language.Tag
+	}{i18n.LanguageFromContext(r.Context())})
 	if err != nil {
 		http.Error(w, `Template error`, http.StatusInternalServerError)
 		return
@@ -48,22 +65,48 @@ func (h *UserHandler) Ping(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusOK)
 }
 
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// readCredentials takes the login and password from a JSON or form body.
+func readCredentials(r *http.Request) (credentials, error) {
+	var creds credentials
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		err := json.NewDecoder(r.Body).Decode(&creds)
+		return creds, err
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return creds, err
+	}
+	creds.Login = r.PostForm.Get("login")
+	creds.Password = r.PostForm.Get("password")
+	return creds, nil
+}
+
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
-	login := r.URL.Query().Get("login")
-	password := r.URL.Query().Get("password")
-	u, err := h.UserRepo.Authorize(login, password)
-	if err == user.ErrNoUser {
-		http.Error(w, `no user`, http.StatusBadRequest)
+	creds, err := readCredentials(r)
+	if err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	u, err := h.UserRepo.Authorize(r.Context(), creds.Login, creds.Password)
+	if errors.Is(err, user.ErrNoUser) || errors.Is(err, user.ErrBadPass) {
+		h.fail(w, r, user.ErrBadCredentials)
 		return
 	}
-	if err == user.ErrBadPass {
-		http.Error(w, `bad pass`, http.StatusBadRequest)
+	if err != nil {
+		h.fail(w, r, err)
 		return
 	}
 
-	sess, err := h.Sessions.Create(w, u)
+	sess, err := h.Sessions.Create(w, r, u)
 	if err != nil {
-		log.Println("err in sess in handlers/user.go")
+		h.fail(w, r, fmt.Errorf("create session: %w", err))
+		return
 	}
 
 	h.Logger.Infof("created session for %v", sess.UserID)
@@ -77,3 +120,389 @@ func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 	http.Redirect(w, r, "/", http.StatusFound)
 }
+
+type UserResponse struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var registerRequest struct {
+		Username  string `json:"username"`
+		Password  string `json:"password"`
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&registerRequest); err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if registerRequest.Username == "" || len(registerRequest.Username) > 50 {
+		h.errSend(w, r, "invalid format username", http.StatusBadRequest)
+		return
+	}
+	if err := user.ValidPassword(registerRequest.Password); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	hash, err := user.HashPassword(registerRequest.Password)
+	if err != nil {
+		h.fail(w, r, fmt.Errorf("hash password: %w", err))
+		return
+	}
+	u := &user.User{
+		Username:     registerRequest.Username,
+		FirstName:    registerRequest.FirstName,
+		LastName:     registerRequest.LastName,
+		PasswordHash: hash,
+	}
+	if err = h.UserRepo.Create(r.Context(), u); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err = json.NewEncoder(w).Encode(UserResponse{
+		ID:        u.ID,
+		Username:  u.Username,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+	}); err != nil {
+		h.Logger.Errorf("json encoding error: %v", err)
+	}
+	h.Logger.Infof("registered user %v", u.Username)
+}
+
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	var changeRequest struct {
+		OldPassword string `json:"oldPassword"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&changeRequest); err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err = user.ValidPassword(changeRequest.NewPassword); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	u, err := h.UserRepo.Authorize(r.Context(), sess.User.Username, changeRequest.OldPassword)
+	if errors.Is(err, user.ErrBadPass) || errors.Is(err, user.ErrNoUser) {
+		h.fail(w, r, apperr.ErrForbidden.WithDetail("wrong password"))
+		return
+	}
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	hash, err := user.HashPassword(changeRequest.NewPassword)
+	if err != nil {
+		h.fail(w, r, fmt.Errorf("hash password: %w", err))
+		return
+	}
+	if err = h.UserRepo.UpdatePassword(r.Context(), u.ID, hash); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if _, err = h.Sessions.RevokeAll(r.Context(), u.ID, sess.ID); err != nil {
+		h.Logger.Errorf("revoke sessions of %v: %v", u.Username, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.Logger.Infof("password changed for %v", u.Username)
+}
+
+// SetLanguage saves the caller's language for messages; an empty language
+// goes back to Accept-Language.
+func (h *UserHandler) SetLanguage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	var languageRequest struct {
+		Language string `json:"language"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&languageRequest); err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if languageRequest.Language != "" {
+		tag, ok := i18n.Parse(languageRequest.Language)
+		if !ok {
+			h.errSend(w, r, "invalid language", http.StatusBadRequest)
+			return
+		}
+		languageRequest.Language = tag.String()
+	}
+
+	if err = h.UserRepo.SetLanguage(r.Context(), sess.User.ID, languageRequest.Language); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(languageRequest); err != nil {
+		h.Logger.Errorf("json encoding error: %v", err)
+	}
+	h.Logger.Infof("language of %v set to %q", sess.User.Username, languageRequest.Language)
+}
+
+type SessionResponse struct {
+	*session.Session
+	Current bool `json:"current"`
+}
+
+// ListSessions lists the caller's active sessions.
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	list, err := h.Sessions.List(r.Context(), sess.UserID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	resp := make([]SessionResponse, 0, len(list))
+	for _, item := range list {
+		resp = append(resp, SessionResponse{Session: item, Current: item.ID == sess.ID})
+	}
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
+		h.Logger.Errorf("json encoding error: %v", err)
+	}
+}
+
+// RevokeSession ends one of the caller's sessions.
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	err = h.Sessions.Revoke(r.Context(), sess.UserID, mux.Vars(r)["sessionID"])
+	if errors.Is(err, session.ErrNoAuth) {
+		h.fail(w, r, apperr.ErrNotFound.WithDetail("the session was not found"))
+		return
+	}
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeSessions ends all of the caller's sessions; with ?keepCurrent=true
+// the one making the request survives.
+func (h *UserHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	sess, err := session.SessionFromContext(r.Context())
+	if err != nil {
+		h.fail(w, r, apperr.ErrUnauthorized)
+		return
+	}
+
+	keepID := ""
+	if r.URL.Query().Get("keepCurrent") == "true" {
+		keepID = sess.ID
+	}
+	revoked, err := h.Sessions.RevokeAll(r.Context(), sess.UserID, keepID)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(map[string]int64{"revoked": revoked}); err != nil {
+		h.Logger.Errorf("json encoding error: %v", err)
+	}
+	h.Logger.Infof("revoked %d sessions of %v", revoked, sess.User.Username)
+}
+
+// RequestPasswordReset issues a one-time reset token. The answer is the same
+// whether or not the user exists. The token is delivered to the user through
+// the Mailer and never logged; with ExposeResetTokens it is also returned in
+// the response.
+func (h *UserHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var resetRequest struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&resetRequest); err != nil || resetRequest.Username == "" {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	u, err := h.UserRepo.GetUserByUsername(r.Context(), resetRequest.Username)
+	switch {
+	case errors.Is(err, user.ErrNoUser):
+		h.Logger.Infof("password reset requested for unknown user %v", resetRequest.Username)
+	case err != nil:
+		h.fail(w, r, err)
+		return
+	default:
+		token, tokenHash, err := user.NewResetToken()
+		if err != nil {
+			h.fail(w, r, fmt.Errorf("generate reset token: %w", err))
+			return
+		}
+		if err = h.UserRepo.SaveResetToken(r.Context(), u.ID, tokenHash, time.Now().Add(user.ResetTokenTTL)); err != nil {
+			h.fail(w, r, err)
+			return
+		}
+		if err = h.Mailer.SendPasswordReset(r.Context(), u, token); err != nil {
+			h.fail(w, r, fmt.Errorf("send password reset: %w", err))
+			return
+		}
+		h.Logger.Infow("password reset token issued", "username", u.Username)
+		if h.ExposeResetTokens {
+			w.WriteHeader(http.StatusAccepted)
+			if err = json.NewEncoder(w).Encode(map[string]string{"token": token}); err != nil {
+				h.Logger.Errorf("json encoding error: %v", err)
+			}
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var resetRequest struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&resetRequest); err != nil || resetRequest.Token == "" {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := user.ValidPassword(resetRequest.NewPassword); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	hash, err := user.HashPassword(resetRequest.NewPassword)
+	if err != nil {
+		h.fail(w, r, fmt.Errorf("hash password: %w", err))
+		return
+	}
+	u, err := h.UserRepo.ResetPassword(r.Context(), user.HashResetToken(resetRequest.Token), hash)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if _, err = h.Sessions.RevokeAll(r.Context(), u.ID, ""); err != nil {
+		h.Logger.Errorf("revoke sessions of %v: %v", u.Username, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.Logger.Infof("password reset for %v", u.Username)
+}
+
+func (h *UserHandler) errSend(w http.ResponseWriter, r *http.Request, reason string, status int) {
+	sendError(w, r, h.Logger, reason, status)
+}
+
+func (h *UserHandler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	fail(w, r, h.Logger, err)
+}
+
+// Token exchanges credentials for a bearer access token and a refresh token.
+func (h *UserHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	creds, err := readCredentials(r)
+	if err != nil {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	u, err := h.UserRepo.Authorize(r.Context(), creds.Login, creds.Password)
+	if errors.Is(err, user.ErrNoUser) || errors.Is(err, user.ErrBadPass) {
+		h.fail(w, r, apperr.ErrUnauthorized.WithDetail("invalid credentials"))
+		return
+	}
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.sendTokens(w, r, u)
+}
+
+// RefreshToken trades a refresh token for a new pair; each refresh token works once.
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var refreshRequest struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&refreshRequest); err != nil || refreshRequest.RefreshToken == "" {
+		h.errSend(w, r, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := h.Tokens.Refresh(r.Context(), refreshRequest.RefreshToken)
+	if errors.Is(err, token.ErrInvalidToken) || errors.Is(err, token.ErrExpiredToken) ||
+		errors.Is(err, token.ErrWrongType) || errors.Is(err, token.ErrReusedToken) {
+		h.fail(w, r, apperr.ErrUnauthorized.WithDetail(err.Error()))
+		return
+	}
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	u, err := h.UserRepo.GetUserByID(r.Context(), claims.Subject)
+	if errors.Is(err, user.ErrNoUser) {
+		h.fail(w, r, apperr.ErrUnauthorized.WithDetail(token.ErrInvalidToken.Error()))
+		return
+	}
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	h.sendTokens(w, r, u)
+}
+
+func (h *UserHandler) sendTokens(w http.ResponseWriter, r *http.Request, u *user.User) {
+	pair, err := h.Tokens.Issue(r.Context(), u.ID, u.Username)
+	if err != nil {
+		h.fail(w, r, fmt.Errorf("sign tokens: %w", err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err = json.NewEncoder(w).Encode(pair); err != nil {
+		h.Logger.Errorf("json encoding error: %v", err)
+	}
+	h.Logger.Infof("issued tokens for %v", u.Username)
+}
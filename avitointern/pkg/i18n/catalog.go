@@ -0,0 +1,176 @@
+package i18n
+
+import (
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message/catalog"
+)
+
+// ru translates the messages as they are written in the code. English needs
+// no entries except for plurals: the key is the English text.
+var ru = map[string]string{
+	// apperr
+	"the request is invalid": "некорректный запрос",
+	"user Unauthorized":      "пользователь не авторизован",
+	"there are not enough permissions to perform the action": "недостаточно прав для выполнения действия",
+	"the resource was not found":                             "ресурс не найден",
+	"the method is not allowed":                              "метод не поддерживается",
+	"the tender was not found":                               "тендер не найден",
+	"the bid was not found":                                  "предложение не найдено",
+	"the organization was not found":                         "организация не найдена",
+	"the version was not found":                              "версия не найдена",
+	"version %d was not found":                               "версия %d не найдена",
+	"the request conflicts with the current state":           "запрос противоречит текущему состоянию",
+	"the tender was modified concurrently":                   "тендер был изменен параллельно",
+	"illegal tender status transition":                       "недопустимая смена статуса тендера",
+	"cannot change tender status from %s to %s":              "нельзя сменить статус тендера с %s на %s",
+	"cannot change tender status from %s to %s: %s":          "нельзя сменить статус тендера с %s на %s: %s",
+	"only the owning organization may do this":               "это может сделать только организация-владелец",
+	"the resource does not match If-Match":                   "ресурс не совпадает с If-Match",
+	"internal server error":                                  "внутренняя ошибка сервера",
+
+	// handlers
+	"bad query in %s":                          "неверное значение параметра «%s»",
+	"invalid format %s":                        "неверный формат поля «%s»",
+	"bad parse version":                        "неверный номер версии",
+	"invalid request body":                     "неверное тело запроса",
+	"missing required fields":                  "не заполнены обязательные поля",
+	"nothing to update":                        "нет изменений",
+	"invalid If-Match header":                  "неверный заголовок If-Match",
+	"invalid credentials":                      "неверный логин или пароль",
+	"wrong password":                           "неверный пароль",
+	"the session was not found":                "сессия не найдена",
+	"the author has no bids on the tender":     "у автора нет предложений по тендеру",
+	"the bid is not published":                 "предложение не опубликовано",
+	"the tender is not open for bids":          "тендер не принимает предложения",
+	"cannot remove the last responsible":       "нельзя удалить последнего ответственного",
+	"the cursor does not match the sort order": "курсор не соответствует порядку сортировки",
+	"cursor and offset cannot be combined":     "курсор нельзя использовать вместе с offset",
+	"request does not match the spec":          "запрос не соответствует спецификации",
+	"invalid language":                         "неподдерживаемый язык",
+
+	// domain errors shown as they are
+	"password must be 8 to 72 bytes long":                             "пароль должен быть длиной от 8 до 72 байт",
+	"reset token is invalid or expired":                               "токен сброса недействителен или истек",
+	"user already exists":                                             "пользователь уже существует",
+	"no user found":                                                   "пользователь не найден",
+	"invald password":                                                 "неверный пароль",
+	"the user is not responsible for the organization":                "пользователь не является ответственным организации",
+	"no session found":                                                "сессия не найдена",
+	"token is invalid":                                                "токен недействителен",
+	"token has expired":                                               "срок действия токена истек",
+	"wrong token type":                                                "неверный тип токена",
+	"refresh token has already been used or was revoked":              "refresh-токен уже использован или отозван",
+	"a tender with this ID already exists":                            "тендер с таким ID уже существует",
+	"an organization with this INN already exists":                    "организация с таким ИНН уже существует",
+	"an organization with this OGRN already exists":                   "организация с таким ОГРН уже существует",
+	"the bid can no longer be changed":                                "предложение больше нельзя изменить",
+	"the bid has been decided":                                        "по предложению уже принято решение",
+	"the bid is canceled":                                             "предложение отменено",
+	"the tender is closed":                                            "тендер закрыт",
+	"cannot change bid status from %s to %s":                          "нельзя сменить статус предложения с %s на %s",
+	"decision on the bid has already been made":                       "решение по предложению уже принято",
+	"user has already submitted a decision":                           "пользователь уже отправил решение",
+	"tender is not published":                                         "тендер не опубликован",
+	"missing organization identifiers":                                "не указаны реквизиты организации",
+	"invalid INN: only digits are allowed":                            "неверный ИНН: допускаются только цифры",
+	"invalid INN: a legal entity has 10 digits":                       "неверный ИНН: у юридического лица 10 цифр",
+	"invalid INN: an individual entrepreneur has 12 digits":           "неверный ИНН: у индивидуального предпринимателя 12 цифр",
+	"invalid INN: checksum mismatch":                                  "неверный ИНН: не сходится контрольная сумма",
+	"invalid OGRN: only digits are allowed":                           "неверный ОГРН: допускаются только цифры",
+	"invalid OGRN: a legal entity has 13 digits starting with 1 or 5": "неверный ОГРН: у юридического лица 13 цифр, первая 1 или 5",
+	"invalid OGRN: an individual entrepreneur has an OGRNIP of 15 digits starting with 3": "неверный ОГРНИП: 15 цифр, первая 3",
+	"invalid OGRN: checksum mismatch":                                          "неверный ОГРН: не сходится контрольная сумма",
+	"invalid KPP: an individual entrepreneur has no KPP":                       "неверный КПП: у индивидуального предпринимателя нет КПП",
+	"invalid KPP: expected 4 digits, 2 digits or capital letters and 3 digits": "неверный КПП: ожидается 4 цифры, 2 цифры или заглавные буквы и 3 цифры",
+
+	// spec validation, the first argument is the field
+	"%s is required":           "поле «%s» обязательно",
+	"%s must be one of: %s":    "поле «%s» должно быть одним из: %s",
+	"%s must be at least %v":   "поле «%s» должно быть не меньше %v",
+	"%s must be at most %v":    "поле «%s» должно быть не больше %v",
+	"%s has an invalid format": "поле «%s» имеет неверный формат",
+	"%s must be of type %s":    "поле «%s» должно иметь тип «%s»",
+	"%s: %s":                   "поле «%s»: %s",
+	"string":                   "строка",
+	"integer":                  "целое число",
+	"number":                   "число",
+	"boolean":                  "логическое значение",
+	"object":                   "объект",
+	"array":                    "массив",
+
+	// templates
+	"User login":      "Вход в систему",
+	"Login":           "Логин",
+	"Password":        "Пароль",
+	"Organization id": "ID организации",
+	"Sign in":         "Войти",
+}
+
+var fieldsRu = map[string]string{
+	"name":              "название",
+	"description":       "описание",
+	"serviceType":       "тип услуги",
+	"service_type":      "тип услуги",
+	"status":            "статус",
+	"organizationId":    "организация",
+	"creatorUsername":   "автор",
+	"author":            "автор",
+	"username":          "пользователь",
+	"tenderId":          "тендер",
+	"bidId":             "предложение",
+	"authorType":        "тип автора",
+	"authorId":          "автор предложения",
+	"authorUsername":    "автор предложений",
+	"requesterUsername": "запрашивающий пользователь",
+	"version":           "версия",
+	"limit":             "лимит",
+	"offset":            "смещение",
+	"decision":          "решение",
+	"bidFeedback":       "отзыв",
+	"cursor":            "курсор",
+	"sort":              "сортировка",
+	"order":             "порядок",
+	"createdFrom":       "создан с",
+	"createdTo":         "создан по",
+	"from":              "начальная версия",
+	"to":                "конечная версия",
+	"type":              "тип",
+	"inn":               "ИНН",
+	"ogrn":              "ОГРН",
+	"kpp":               "КПП",
+	"login":             "логин",
+	"password":          "пароль",
+	"lang":              "язык",
+	"body":              "тело запроса",
+}
+
+// Length limits agree with the number: "1 character", "2 characters" and
+// "1 символа", "2 символов", "5 символов".
+const (
+	atMostChars  = "%s must be at most %d characters"
+	atLeastChars = "%s must be at least %d characters"
+)
+
+var messages = newCatalog()
+
+func newCatalog() *catalog.Builder {
+	b := catalog.NewBuilder(catalog.Fallback(language.English))
+	for key, msg := range ru {
+		_ = b.SetString(language.Russian, key, msg)
+	}
+
+	_ = b.Set(language.English, atMostChars, plural.Selectf(2, "%d",
+		plural.One, "%[1]s must be at most %[2]d character",
+		plural.Other, "%[1]s must be at most %[2]d characters"))
+	_ = b.Set(language.English, atLeastChars, plural.Selectf(2, "%d",
+		plural.One, "%[1]s must be at least %[2]d character",
+		plural.Other, "%[1]s must be at least %[2]d characters"))
+	_ = b.Set(language.Russian, atMostChars, plural.Selectf(2, "%d",
+		plural.One, "поле «%[1]s» должно быть не длиннее %[2]d символа",
+		plural.Other, "поле «%[1]s» должно быть не длиннее %[2]d символов"))
+	_ = b.Set(language.Russian, atLeastChars, plural.Selectf(2, "%d",
+		plural.One, "поле «%[1]s» должно быть не короче %[2]d символа",
+		plural.Other, "поле «%[1]s» должно быть не короче %[2]d символов"))
+	return b
+}
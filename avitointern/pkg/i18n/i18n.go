@@ -0,0 +1,116 @@
+package i18n
+
+import (
+	"context"
+	"html/template"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Supported lists the languages we have messages for. The first one is the
+// fallback: most of our users speak Russian.
+var Supported = []language.Tag{language.Russian, language.English}
+
+var matcher = language.NewMatcher(Supported)
+
+// Match picks a supported language from the preferences in order: each is a
+// language tag or an Accept-Language value, empty ones are skipped. When none
+// matches, the fallback language is returned.
+func Match(prefs ...string) language.Tag {
+	for _, pref := range prefs {
+		if pref == "" {
+			continue
+		}
+		tags, _, err := language.ParseAcceptLanguage(pref)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+		if _, i, confidence := matcher.Match(tags...); confidence != language.No {
+			return Supported[i]
+		}
+	}
+	return Supported[0]
+}
+
+// Parse accepts only the supported languages themselves, as a user setting.
+func Parse(lang string) (language.Tag, bool) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return language.Und, false
+	}
+	for _, supported := range Supported {
+		if tag == supported {
+			return tag, true
+		}
+	}
+	return language.Und, false
+}
+
+type ctxKey int
+
+const languageKey ctxKey = 1
+
+func ContextWithLanguage(ctx context.Context, tag language.Tag) context.Context {
+	return context.WithValue(ctx, languageKey, tag)
+}
+
+// LanguageFromContext returns the request language, the fallback when the
+// request went around the middleware.
+func LanguageFromContext(ctx context.Context) language.Tag {
+	tag, ok := ctx.Value(languageKey).(language.Tag)
+	if !ok {
+		return Supported[0]
+	}
+	return tag
+}
+
+// NewPrinter formats messages in tag. Keys are the English messages, so a
+// missing translation shows the English text.
+func NewPrinter(tag language.Tag) *message.Printer {
+	return message.NewPrinter(tag, message.Catalog(messages))
+}
+
+// fieldMessages end with a field name, as in "invalid format status"; the
+// handlers build them by concatenation and the field is translated apart.
+var fieldMessages = []string{"invalid format %s", "bad query in %s"}
+
+// Sprintf translates the message to tag. A message without arguments is
+// taken as a plain text, so a "%" in an error message built elsewhere stays.
+func Sprintf(tag language.Tag, format string, args ...interface{}) string {
+	p := NewPrinter(tag)
+	if len(args) > 0 {
+		return p.Sprintf(format, args...)
+	}
+	for _, msg := range fieldMessages {
+		field, ok := strings.CutPrefix(format, strings.TrimSuffix(msg, "%s"))
+		if ok && field != "" && !strings.ContainsRune(field, ' ') {
+			return p.Sprintf(msg, Field(tag, field))
+		}
+	}
+	if strings.ContainsRune(format, '%') {
+		return format
+	}
+	return p.Sprintf(format)
+}
+
+// Field is the name of a request field or parameter as the user sees it.
+// The API names are kept for English, they are what clients send.
+func Field(tag language.Tag, name string) string {
+	if tag == language.Russian {
+		if label, ok := fieldsRu[name]; ok {
+			return label
+		}
+	}
+	return name
+}
+
+// TemplateFuncs adds "t" to the templates: {{t .Lang "User login"}}.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"t": func(tag language.Tag, key string, args ...interface{}) string {
+			return Sprintf(tag, key, args...)
+		},
+	}
+}
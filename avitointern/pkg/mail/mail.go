@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"sync"
+
+	"avitointern/pkg/user"
+
+	"go.uber.org/zap"
+)
+
+// Mailer delivers messages to users. The reset token is a credential: it must
+// only ever reach the user, never the logs.
+type Mailer interface {
+	SendPasswordReset(ctx context.Context, to *user.User, token string) error
+}
+
+// Stub stands in until real delivery is configured. It keeps the last token
+// per username in memory, for tests, and logs only that a message was sent.
+type Stub struct {
+	Logger *zap.SugaredLogger
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+var _ Mailer = &Stub{}
+
+func NewStub(logger *zap.SugaredLogger) *Stub {
+	return &Stub{Logger: logger, tokens: make(map[string]string)}
+}
+
+func (s *Stub) SendPasswordReset(_ context.Context, to *user.User, token string) error {
+	s.mu.Lock()
+	s.tokens[to.Username] = token
+	s.mu.Unlock()
+
+	s.Logger.Infow("password reset mail is not delivered, no mailer configured", "username", to.Username)
+	return nil
+}
+
+// LastResetToken returns the last token sent to username.
+func (s *Stub) LastResetToken(username string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[username]
+	return token, ok
+}
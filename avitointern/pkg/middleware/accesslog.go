@@ -1,16 +1,16 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 	"time"
 
+	"avitointern/pkg/apperr"
+
 	"go.uber.org/zap"
 )
 
 func AccessLog(logger *zap.SugaredLogger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("access log middleware")
 		start := time.Now()
 		next.ServeHTTP(w, r)
 		logger.Infow("New request",
@@ -18,6 +18,7 @@ func AccessLog(logger *zap.SugaredLogger, next http.Handler) http.Handler {
 			"remote_addr", r.RemoteAddr,
 			"url", r.URL.Path,
 			"time", time.Since(start),
+			"request_id", apperr.RequestIDFromContext(r.Context()),
 		)
 	})
 }
@@ -1,36 +1,73 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
+	"strings"
 
+	"avitointern/pkg/apperr"
 	"avitointern/pkg/session"
+	"avitointern/pkg/token"
+	"avitointern/pkg/user"
 )
 
-var (
-	noAuthUrls = map[string]struct{}{
-		"/login": struct{}{},
-	}
-	noSessUrls = map[string]struct{}{
-		"/": struct{}{},
-	}
-)
-
-func Auth(sm *session.SessionsManager, next http.Handler) http.Handler {
+// Auth resolves the caller into a session.Session: from an
+// "Authorization: Bearer" access token when the header is present,
+// otherwise from the session_id cookie. Requests without either get 401;
+// public endpoints are mounted without this middleware.
+func Auth(sm *session.SessionsManager, tokens *token.Manager, users user.UserRepo, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("auth middleware")
-		if _, ok := noAuthUrls[r.URL.Path]; ok {
-			next.ServeHTTP(w, r)
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			sess, err := bearerSession(r, tokens, users, authorization)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				unauthorized(w, r, err.Error())
+				return
+			}
+			next.ServeHTTP(w, withSession(w, r, sess))
 			return
 		}
 		sess, err := sm.Check(r)
-		_, canbeWithouthSess := noSessUrls[r.URL.Path]
-		if err != nil && !canbeWithouthSess {
-			fmt.Println("no auth")
-			http.Redirect(w, r, "/", http.StatusFound)
+		if err != nil {
+			unauthorized(w, r, "user Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, withSession(w, r, sess))
+	})
+}
+
+// Session attaches the cookie session when there is one and lets anonymous
+// requests through, for the HTML pages.
+func Session(sm *session.SessionsManager, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, err := sm.Check(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
 			return
 		}
-		ctx := session.ContextWithSession(r.Context(), sess)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, withSession(w, r, sess))
 	})
 }
+
+func unauthorized(w http.ResponseWriter, r *http.Request, reason string) {
+	_ = apperr.NewProblem(r, apperr.ErrUnauthorized.WithDetail(reason)).Write(w)
+}
+
+func bearerSession(r *http.Request, tokens *token.Manager, users user.UserRepo, authorization string) (*session.Session, error) {
+	raw, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return nil, token.ErrInvalidToken
+	}
+	claims, err := tokens.Verify(strings.TrimSpace(raw), token.Access)
+	if err != nil {
+		return nil, err
+	}
+	u, err := users.GetUserByID(r.Context(), claims.Subject)
+	if err != nil {
+		return nil, token.ErrInvalidToken
+	}
+	return &session.Session{
+		ID:     claims.ID,
+		UserID: u.ID,
+		User:   u,
+	}, nil
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"avitointern/pkg/i18n"
+	"avitointern/pkg/session"
+)
+
+// Language picks the response language from Accept-Language. Auth and
+// Session replace it with the user's setting when the user has one.
+func Language(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := i18n.Match(r.Header.Get("Accept-Language"))
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Set("Content-Language", tag.String())
+		next.ServeHTTP(w, r.WithContext(i18n.ContextWithLanguage(r.Context(), tag)))
+	})
+}
+
+func withSession(w http.ResponseWriter, r *http.Request, sess *session.Session) *http.Request {
+	ctx := session.ContextWithSession(r.Context(), sess)
+	if sess.User != nil && sess.User.Language != "" {
+		tag := i18n.Match(sess.User.Language)
+		w.Header().Set("Content-Language", tag.String())
+		ctx = i18n.ContextWithLanguage(ctx, tag)
+	}
+	return r.WithContext(ctx)
+}
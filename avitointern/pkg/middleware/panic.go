@@ -1,20 +1,25 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
+
+	"avitointern/pkg/apperr"
+
+	"go.uber.org/zap"
 )
 
-func Panic(next http.Handler) http.Handler {
+func Panic(logger *zap.SugaredLogger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("panicMiddleware", r.URL.Path)
 		defer func() {
 			if err := recover(); err != nil {
-				fmt.Println("recovered", err)
-				http.Error(w, "Internal server error", 500)
+				logger.Errorw("recovered from panic",
+					"panic", err,
+					"url", r.URL.Path,
+					"request_id", apperr.RequestIDFromContext(r.Context()),
+				)
+				apperr.Write(w, r, apperr.ErrInternal)
 			}
 		}()
 		next.ServeHTTP(w, r)
-		fmt.Println()
 	})
 }
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"avitointern/pkg/apperr"
+
+	"github.com/google/uuid"
+)
+
+const maxRequestIDLen = 128
+
+// RequestID keeps the caller's X-Request-ID when it looks sane, otherwise
+// issues a new one. The ID is echoed back and ends up in error bodies and
+// the access log.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(apperr.ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
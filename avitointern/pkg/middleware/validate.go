@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/i18n"
+	"avitointern/pkg/openapi"
+
+	"go.uber.org/zap"
+)
+
+// Validate rejects requests that do not match their operation in the spec
+// with 400 and the list of offending fields. With debug set it also checks
+// the responses we send and logs every mismatch; the response itself is
+// passed through unchanged.
+func Validate(spec *openapi.Spec, debug bool, logger *zap.SugaredLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, ok := spec.Operation(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if errs := op.ValidateRequest(r.Context()); len(errs) > 0 {
+			p := apperr.NewProblem(r, apperr.ErrBadRequest.WithDetail("request does not match the spec"))
+			p.Errors = openapi.Localize(i18n.LanguageFromContext(r.Context()), errs)
+			_ = p.Write(w)
+			return
+		}
+
+		if !debug {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if errs := op.ValidateResponse(r.Context(), rec.status, rec.header, bytes.NewReader(rec.body.Bytes())); len(errs) > 0 {
+			logger.Warnw("response does not match the spec",
+				"operation", op.ID(),
+				"status", rec.status,
+				"errors", errs,
+			)
+		}
+
+		for key, values := range rec.header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(rec.body.Bytes())
+	})
+}
+
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status = status
+	rec.wroteHeader = true
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(data)
+}
@@ -0,0 +1,291 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the pg_advisory_lock key ("avit" in ASCII), so that replicas
+// started at the same time don't apply the same migration twice.
+const lockID int64 = 0x61766974
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum VARCHAR(64) NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+var (
+	ErrChecksumMismatch = errors.New("checksum of an applied migration has changed")
+	ErrUnknownMigration = errors.New("applied migration is missing from the binary")
+)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type applied struct {
+	version   int
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	DB *sql.DB
+}
+
+func New(db *sql.DB) *Migrator {
+	return &Migrator{DB: db}
+}
+
+// Load reads the embedded migrations. Files are named NNNN_name.up.sql and
+// NNNN_name.down.sql; both halves are required.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("unexpected migration file %q", fileName)
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration file %q has no name", fileName)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration file %q has a bad version", fileName)
+		}
+
+		body, err := fs.ReadFile(files, path.Join("sql", fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d must have both up and down files", m.Version)
+		}
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Up applies every pending migration in order and returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn, all []Migration, state map[int]applied) error {
+		for _, mig := range all {
+			if _, ok := state[mig.Version]; ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+					mig.Version, mig.Name, mig.Checksum)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn, all []Migration, state map[int]applied) error {
+		for i := len(all) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := all[i]
+			if _, ok := state[mig.Version]; !ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(_ *sql.Conn, all []Migration, state map[int]applied) error {
+		for _, mig := range all {
+			st := Status{Version: mig.Version, Name: mig.Name}
+			if a, ok := state[mig.Version]; ok {
+				st.Applied = true
+				st.AppliedAt = a.appliedAt
+			}
+			statuses = append(statuses, st)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock takes the advisory lock on a dedicated connection, makes sure the
+// history table exists and verifies the checksums of applied migrations.
+func (m *Migrator) withLock(ctx context.Context,
+	fn func(conn *sql.Conn, all []Migration, state map[int]applied) error) (err error) {
+	all, err := Load()
+	if err != nil {
+		return err
+	}
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := conn.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer func() {
+		_, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+
+	state, err := loadApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err = verify(all, state); err != nil {
+		return err
+	}
+
+	return fn(conn, all, state)
+}
+
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int]applied, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	state := make(map[int]applied)
+	for rows.Next() {
+		var a applied
+		if err = rows.Scan(&a.version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		state[a.version] = a
+	}
+	return state, rows.Err()
+}
+
+func verify(all []Migration, state map[int]applied) error {
+	known := make(map[int]Migration, len(all))
+	for _, mig := range all {
+		known[mig.Version] = mig
+	}
+	for version, a := range state {
+		mig, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+		if mig.Checksum != a.checksum {
+			return fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
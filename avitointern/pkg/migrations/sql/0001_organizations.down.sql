@@ -0,0 +1,2 @@
+-- The tables belong to the target database and may hold its data, so the
+-- first migration is never undone: nothing is dropped here.
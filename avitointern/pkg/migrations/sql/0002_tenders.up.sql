@@ -0,0 +1,38 @@
+-- Like 0001, this migration can run over tables that already exist.
+CREATE TABLE IF NOT EXISTS tenders (
+    tender_id VARCHAR(100) PRIMARY KEY,
+    tender_name VARCHAR(100) NOT NULL,
+    tender_description VARCHAR(500) NOT NULL,
+    service_type VARCHAR(20) NOT NULL CHECK (service_type IN ('Construction', 'Delivery', 'Manufacture')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('Created', 'Published', 'Closed')),
+    organization_id VARCHAR(100) NOT NULL,
+    version INT NOT NULL DEFAULT 1 CHECK (version >= 1),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    author VARCHAR(50) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tenders_author_idx ON tenders (author);
+CREATE INDEX IF NOT EXISTS tenders_organization_id_idx ON tenders (organization_id);
+
+CREATE TABLE IF NOT EXISTS tender_versions (
+    tender_id VARCHAR(100) NOT NULL REFERENCES tenders(tender_id) ON DELETE CASCADE,
+    version INT NOT NULL CHECK (version >= 1),
+    tender_name VARCHAR(100) NOT NULL,
+    tender_description VARCHAR(500) NOT NULL,
+    service_type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    modified_by VARCHAR(50) NOT NULL DEFAULT '',
+    modified_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rollback_of INT
+);
+
+-- A tender_versions table that predates this migration may lack the key, so
+-- it is added on its own.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tender_versions_tender_id_version_key') THEN
+        ALTER TABLE tender_versions
+            ADD CONSTRAINT tender_versions_tender_id_version_key UNIQUE (tender_id, version);
+    END IF;
+END
+$$;
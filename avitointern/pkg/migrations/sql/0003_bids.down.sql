@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS bid_reviews;
+DROP TABLE IF EXISTS bid_decisions;
+DROP TABLE IF EXISTS bid_versions;
+DROP TABLE IF EXISTS bids;
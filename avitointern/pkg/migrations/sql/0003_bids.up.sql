@@ -0,0 +1,42 @@
+CREATE TABLE bids (
+    bid_id VARCHAR(100) PRIMARY KEY,
+    bid_name VARCHAR(100) NOT NULL,
+    bid_description VARCHAR(500) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('Created', 'Published', 'Canceled')),
+    tender_id VARCHAR(100) NOT NULL REFERENCES tenders(tender_id) ON DELETE CASCADE,
+    author_type VARCHAR(20) NOT NULL CHECK (author_type IN ('Organization', 'User')),
+    author_id VARCHAR(100) NOT NULL,
+    version INT NOT NULL DEFAULT 1 CHECK (version >= 1),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    creator_username VARCHAR(50) NOT NULL
+);
+
+CREATE INDEX bids_tender_id_idx ON bids (tender_id);
+CREATE INDEX bids_creator_username_idx ON bids (creator_username);
+
+CREATE TABLE bid_versions (
+    bid_id VARCHAR(100) NOT NULL REFERENCES bids(bid_id) ON DELETE CASCADE,
+    version INT NOT NULL CHECK (version >= 1),
+    bid_name VARCHAR(100) NOT NULL,
+    bid_description VARCHAR(500) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    CONSTRAINT bid_versions_bid_id_version_key UNIQUE (bid_id, version)
+);
+
+CREATE TABLE bid_decisions (
+    bid_id VARCHAR(100) NOT NULL REFERENCES bids(bid_id) ON DELETE CASCADE,
+    username VARCHAR(50) NOT NULL,
+    decision VARCHAR(20) NOT NULL CHECK (decision IN ('Approved', 'Rejected')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT bid_decisions_bid_id_username_key UNIQUE (bid_id, username)
+);
+
+CREATE TABLE bid_reviews (
+    review_id VARCHAR(100) PRIMARY KEY,
+    bid_id VARCHAR(100) NOT NULL REFERENCES bids(bid_id) ON DELETE CASCADE,
+    username VARCHAR(50) NOT NULL,
+    description VARCHAR(1000) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX bid_reviews_bid_id_idx ON bid_reviews (bid_id);
@@ -0,0 +1 @@
+ALTER TABLE employee DROP COLUMN password;
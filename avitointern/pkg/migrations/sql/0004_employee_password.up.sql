@@ -0,0 +1 @@
+ALTER TABLE employee ADD COLUMN password VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS password_reset_tokens;
+
+-- Hashes cannot be turned back into passwords: every employee has to get a new one.
+ALTER TABLE employee RENAME COLUMN password_hash TO password;
+UPDATE employee SET password = '';
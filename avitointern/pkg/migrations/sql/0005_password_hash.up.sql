@@ -0,0 +1,15 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- crypt() with a 'bf' salt produces bcrypt hashes that golang.org/x/crypto/bcrypt accepts.
+UPDATE employee SET password = crypt(password, gen_salt('bf', 10)) WHERE password <> '';
+ALTER TABLE employee RENAME COLUMN password TO password_hash;
+
+CREATE TABLE password_reset_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS organization_viewer;
+
+ALTER TABLE employee DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,10 @@
+ALTER TABLE employee ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE organization_viewer (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organization(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES employee(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX organization_viewer_org_user_key ON organization_viewer (organization_id, user_id);
+CREATE INDEX organization_viewer_user_id_idx ON organization_viewer (user_id);
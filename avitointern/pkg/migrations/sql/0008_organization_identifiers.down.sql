@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS organization_ogrn_key;
+DROP INDEX IF EXISTS organization_inn_key;
+
+ALTER TABLE organization DROP COLUMN IF EXISTS kpp;
+ALTER TABLE organization DROP COLUMN IF EXISTS ogrn;
+ALTER TABLE organization DROP COLUMN IF EXISTS inn;
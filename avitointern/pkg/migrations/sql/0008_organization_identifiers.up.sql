@@ -0,0 +1,8 @@
+ALTER TABLE organization ADD COLUMN inn VARCHAR(12);
+ALTER TABLE organization ADD COLUMN ogrn VARCHAR(15);
+ALTER TABLE organization ADD COLUMN kpp VARCHAR(9);
+
+-- KPP is shared by organizations registered with the same tax office, so only
+-- INN and OGRN identify an organization.
+CREATE UNIQUE INDEX organization_inn_key ON organization (inn) WHERE inn IS NOT NULL;
+CREATE UNIQUE INDEX organization_ogrn_key ON organization (ogrn) WHERE ogrn IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE employee ADD COLUMN language VARCHAR(8) NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- The outcome of the decisions on a bid, set once by the decision that
+-- resolves it. Bids decided before the column existed are resolved from their
+-- decisions with the current number of responsibles.
+ALTER TABLE bids ADD COLUMN decision VARCHAR(20) CHECK (decision IN ('Approved', 'Rejected'));
+
+UPDATE bids b SET decision = 'Rejected'
+WHERE EXISTS (SELECT 1 FROM bid_decisions d WHERE d.bid_id = b.bid_id AND d.decision = 'Rejected');
+
+UPDATE bids b SET decision = 'Approved'
+WHERE b.decision IS NULL
+  AND (SELECT count(*) FROM bid_decisions d WHERE d.bid_id = b.bid_id AND d.decision = 'Approved') >=
+      GREATEST(1, LEAST(3, (SELECT count(*) FROM organization_responsible r
+                            JOIN tenders t ON r.organization_id::text = t.organization_id
+                            WHERE t.tender_id = b.tender_id)));
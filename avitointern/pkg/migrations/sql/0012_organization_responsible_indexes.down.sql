@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS organization_responsible_user_id_idx;
+DROP INDEX IF EXISTS organization_responsible_org_user_key;
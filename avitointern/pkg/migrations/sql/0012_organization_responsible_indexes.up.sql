@@ -0,0 +1,10 @@
+-- A user is responsible for an organization at most once. Duplicate rows left
+-- from before the key are dropped first.
+DELETE FROM organization_responsible a
+USING organization_responsible b
+WHERE a.organization_id = b.organization_id
+  AND a.user_id = b.user_id
+  AND a.ctid > b.ctid;
+
+CREATE UNIQUE INDEX IF NOT EXISTS organization_responsible_org_user_key ON organization_responsible (organization_id, user_id);
+CREATE INDEX IF NOT EXISTS organization_responsible_user_id_idx ON organization_responsible (user_id);
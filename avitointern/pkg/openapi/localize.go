@@ -0,0 +1,50 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"avitointern/pkg/i18n"
+
+	"golang.org/x/text/language"
+)
+
+// Localize rewrites the reasons in tag, naming the field in that language.
+// Rules without a message keep the validator's English reason.
+func Localize(tag language.Tag, errs []FieldError) []FieldError {
+	out := make([]FieldError, len(errs))
+	for i, fe := range errs {
+		fe.Reason = fe.localize(tag)
+		out[i] = fe
+	}
+	return out
+}
+
+func (fe FieldError) localize(tag language.Tag) string {
+	field := i18n.Field(tag, fe.Field)
+	s := fe.schema
+	switch {
+	case fe.Rule == "required":
+		return i18n.Sprintf(tag, "%s is required", field)
+	case s == nil:
+	case fe.Rule == "maxLength" && s.MaxLength != nil:
+		return i18n.Sprintf(tag, "%s must be at most %d characters", field, int(*s.MaxLength))
+	case fe.Rule == "minLength":
+		return i18n.Sprintf(tag, "%s must be at least %d characters", field, int(s.MinLength))
+	case fe.Rule == "maximum" && s.Max != nil:
+		return i18n.Sprintf(tag, "%s must be at most %v", field, *s.Max)
+	case fe.Rule == "minimum" && s.Min != nil:
+		return i18n.Sprintf(tag, "%s must be at least %v", field, *s.Min)
+	case fe.Rule == "enum":
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		return i18n.Sprintf(tag, "%s must be one of: %s", field, strings.Join(values, ", "))
+	case fe.Rule == "format", fe.Rule == "pattern":
+		return i18n.Sprintf(tag, "%s has an invalid format", field)
+	case fe.Rule == "type" && s.Type != nil && len(s.Type.Slice()) == 1:
+		return i18n.Sprintf(tag, "%s must be of type %s", field, i18n.Sprintf(tag, s.Type.Slice()[0]))
+	}
+	return i18n.Sprintf(tag, "%s: %s", field, fe.Reason)
+}
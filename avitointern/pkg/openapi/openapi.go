@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// embedded is a copy of задание/openapi.yml built into the binary, so the
+// server does not depend on the working directory or on the spec being
+// shipped next to it.
+//
+//go:generate cp ../../../задание/openapi.yml openapi.yml
+//go:embed openapi.yml
+var embedded []byte
+
+// FieldError describes one violation of the spec. Field is the parameter
+// name or the dotted path into the JSON body ("body" for the body as a whole).
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+	// Rule is the schema keyword the value broke, e.g. "required" or
+	// "maxLength"; schema is where its limits are.
+	Rule   string `json:"rule,omitempty"`
+	schema *openapi3.Schema
+}
+
+// Spec validates requests and responses against the operations of an
+// OpenAPI document. Requests to paths the document does not describe are not
+// checked.
+type Spec struct {
+	doc    *openapi3.T
+	router routers.Router
+}
+
+// Load reads and validates the document. Examples are not checked, some of
+// them in the assignment spec are incomplete. The servers section is replaced
+// with prefixes, the paths the API is actually mounted at.
+func Load(path string, prefixes ...string) (*Spec, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return newSpec(loader, doc, path, prefixes)
+}
+
+// LoadEmbedded is Load for the spec built into the binary.
+func LoadEmbedded(prefixes ...string) (*Spec, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(embedded)
+	if err != nil {
+		return nil, fmt.Errorf("load embedded spec: %w", err)
+	}
+	return newSpec(loader, doc, "embedded", prefixes)
+}
+
+func newSpec(loader *openapi3.Loader, doc *openapi3.T, name string, prefixes []string) (*Spec, error) {
+	if err := doc.Validate(loader.Context, openapi3.DisableExamplesValidation()); err != nil {
+		return nil, fmt.Errorf("invalid spec %s: %w", name, err)
+	}
+	doc.Servers = nil
+	for _, prefix := range prefixes {
+		doc.Servers = append(doc.Servers, &openapi3.Server{URL: prefix})
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Spec{doc: doc, router: router}, nil
+}
+
+// Doc returns the loaded document.
+func (s *Spec) Doc() *openapi3.T {
+	return s.doc
+}
+
+// Operation finds the operation that serves r. The boolean is false when the
+// spec does not describe the path or method.
+func (s *Spec) Operation(r *http.Request) (*Operation, bool) {
+	route, params, err := s.router.FindRoute(r)
+	if err != nil {
+		return nil, false
+	}
+	return &Operation{input: &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: params,
+		Route:      route,
+		Options:    options(),
+	}}, true
+}
+
+type Operation struct {
+	input *openapi3filter.RequestValidationInput
+}
+
+func (o *Operation) ID() string {
+	return o.input.Route.Operation.OperationID
+}
+
+// ValidateRequest checks the path, query and body parameters. The body is
+// left readable for the handler.
+func (o *Operation) ValidateRequest(ctx context.Context) []FieldError {
+	return fieldErrors(openapi3filter.ValidateRequest(ctx, o.input))
+}
+
+// ValidateResponse checks a response the handler produced for the request.
+func (o *Operation) ValidateResponse(ctx context.Context, status int, header http.Header, body io.Reader) []FieldError {
+	return fieldErrors(openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: o.input,
+		Status:                 status,
+		Header:                 header,
+		Body:                   io.NopCloser(body),
+		Options:                o.input.Options,
+	}))
+}
+
+func options() *openapi3filter.Options {
+	return &openapi3filter.Options{
+		MultiError:            true,
+		IncludeResponseStatus: true,
+		SkipSettingDefaults:   true,
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+	}
+}
+
+func fieldErrors(err error) []FieldError {
+	if err == nil {
+		return nil
+	}
+	var out []FieldError
+	collect(err, "", &out)
+	return out
+}
+
+// collect flattens err into out. The wrappers are matched directly rather
+// than with errors.As, which would see through a RequestError to the schema
+// errors inside and lose the parameter name.
+func collect(err error, field string, out *[]FieldError) {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		for _, inner := range e {
+			collect(inner, field, out)
+		}
+	case *openapi3filter.RequestError:
+		switch {
+		case e.Parameter != nil:
+			field = e.Parameter.Name
+		case e.RequestBody != nil:
+			field = "body"
+		}
+		if e.Err == nil {
+			*out = append(*out, FieldError{Field: field, Reason: e.Reason})
+			return
+		}
+		collect(e.Err, field, out)
+	case *openapi3filter.ResponseError:
+		if e.Err == nil {
+			*out = append(*out, FieldError{Field: "response", Reason: e.Reason})
+			return
+		}
+		collect(e.Err, "body", out)
+	case *openapi3.SchemaError:
+		if pointer := e.JSONPointer(); len(pointer) > 0 && field == "body" {
+			field = strings.Join(pointer, ".")
+		}
+		*out = append(*out, FieldError{Field: field, Reason: e.Reason, Rule: e.SchemaField, schema: e.Schema})
+	case *openapi3filter.ParseError:
+		*out = append(*out, FieldError{Field: field, Reason: e.Error()})
+	default:
+		if errors.Is(err, openapi3filter.ErrInvalidRequired) {
+			*out = append(*out, FieldError{Field: field, Reason: "is required", Rule: "required"})
+			return
+		}
+		*out = append(*out, FieldError{Field: field, Reason: err.Error()})
+	}
+}
@@ -0,0 +1,140 @@
+package organizations
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrBadINN  = errors.New("invalid INN")
+	ErrBadOGRN = errors.New("invalid OGRN")
+	ErrBadKPP  = errors.New("invalid KPP")
+)
+
+var (
+	inn10Weights = []int{2, 4, 10, 3, 5, 9, 4, 6, 8}
+	inn11Weights = []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}
+	inn12Weights = []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}
+)
+
+// ValidateIdentifiers checks INN, OGRN and KPP against the organization type:
+// an individual entrepreneur (IE) has a 12 digit INN, a 15 digit OGRNIP in the
+// OGRN field and no KPP; a legal entity has a 10 digit INN, a 13 digit OGRN
+// and a KPP. Organizations created before identifiers existed may lack them,
+// so empty values only fail when required is set.
+func ValidateIdentifiers(org *Organization, required bool) error {
+	legalEntity := org.Type != IE
+
+	if required && (org.INN == "" || org.OGRN == "" || legalEntity && org.KPP == "") {
+		return errors.New("missing organization identifiers")
+	}
+
+	if org.INN != "" {
+		if err := validINN(org.INN, legalEntity); err != nil {
+			return err
+		}
+	}
+	if org.OGRN != "" {
+		if err := validOGRN(org.OGRN, legalEntity); err != nil {
+			return err
+		}
+	}
+	if org.KPP != "" {
+		if !legalEntity {
+			return fmt.Errorf("%w: an individual entrepreneur has no KPP", ErrBadKPP)
+		}
+		if !validKPP(org.KPP) {
+			return fmt.Errorf("%w: expected 4 digits, 2 digits or capital letters and 3 digits", ErrBadKPP)
+		}
+	}
+	return nil
+}
+
+func validINN(inn string, legalEntity bool) error {
+	digits, ok := parseDigits(inn)
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: only digits are allowed", ErrBadINN)
+	case legalEntity && len(digits) != 10:
+		return fmt.Errorf("%w: a legal entity has 10 digits", ErrBadINN)
+	case !legalEntity && len(digits) != 12:
+		return fmt.Errorf("%w: an individual entrepreneur has 12 digits", ErrBadINN)
+	}
+
+	if legalEntity {
+		if checkDigit(digits, inn10Weights) != digits[9] {
+			return fmt.Errorf("%w: checksum mismatch", ErrBadINN)
+		}
+		return nil
+	}
+	if checkDigit(digits, inn11Weights) != digits[10] || checkDigit(digits, inn12Weights) != digits[11] {
+		return fmt.Errorf("%w: checksum mismatch", ErrBadINN)
+	}
+	return nil
+}
+
+// validOGRN checks an OGRN (13 digits, modulus 11) or an OGRNIP (15 digits,
+// modulus 13): the last digit is the remainder of the rest, taken mod 10.
+func validOGRN(ogrn string, legalEntity bool) error {
+	digits, ok := parseDigits(ogrn)
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: only digits are allowed", ErrBadOGRN)
+	case legalEntity && (len(digits) != 13 || digits[0] != 1 && digits[0] != 5):
+		return fmt.Errorf("%w: a legal entity has 13 digits starting with 1 or 5", ErrBadOGRN)
+	case !legalEntity && (len(digits) != 15 || digits[0] != 3):
+		return fmt.Errorf("%w: an individual entrepreneur has an OGRNIP of 15 digits starting with 3", ErrBadOGRN)
+	}
+
+	modulus := 11
+	if !legalEntity {
+		modulus = 13
+	}
+	rest := 0
+	for _, d := range digits[:len(digits)-1] {
+		rest = (rest*10 + d) % modulus
+	}
+	if rest%10 != digits[len(digits)-1] {
+		return fmt.Errorf("%w: checksum mismatch", ErrBadOGRN)
+	}
+	return nil
+}
+
+// validKPP checks the format NNNNPPNNN, where PP may hold capital Latin
+// letters since 2019.
+func validKPP(kpp string) bool {
+	if len(kpp) != 9 {
+		return false
+	}
+	for i := 0; i < len(kpp); i++ {
+		c := kpp[i]
+		isDigit := c >= '0' && c <= '9'
+		if i == 4 || i == 5 {
+			if !isDigit && (c < 'A' || c > 'Z') {
+				return false
+			}
+		} else if !isDigit {
+			return false
+		}
+	}
+	return true
+}
+
+func checkDigit(digits, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += digits[i] * w
+	}
+	return sum % 11 % 10
+}
+
+func parseDigits(s string) ([]int, bool) {
+	digits := make([]int, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return nil, false
+		}
+		digits = append(digits, int(s[i]-'0'))
+	}
+	return digits, len(digits) > 0
+}
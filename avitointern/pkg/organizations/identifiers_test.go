@@ -0,0 +1,50 @@
+package organizations_test
+
+import (
+	"testing"
+
+	"avitointern/pkg/organizations"
+)
+
+func TestValidateIdentifiers(t *testing.T) {
+	cases := []struct {
+		name     string
+		org      organizations.Organization
+		required bool
+		want     string
+	}{
+		// Sberbank: INN 7707083893, OGRN 1027700132195, KPP 773601001.
+		{"legal entity", organizations.Organization{Type: organizations.JSC, INN: "7707083893", OGRN: "1027700132195", KPP: "773601001"}, true, ""},
+		{"KPP with letters", organizations.Organization{Type: organizations.LLC, INN: "7707083893", OGRN: "1027700132195", KPP: "7736AB001"}, true, ""},
+		{"individual entrepreneur", organizations.Organization{Type: organizations.IE, INN: "500100732259", OGRN: "304500116000157"}, true, ""},
+		{"no identifiers", organizations.Organization{Type: organizations.LLC}, false, ""},
+
+		{"missing KPP", organizations.Organization{Type: organizations.LLC, INN: "7707083893", OGRN: "1027700132195"}, true, "missing organization identifiers"},
+		{"INN with letters", organizations.Organization{Type: organizations.LLC, INN: "77070838A3"}, false, "invalid INN: only digits are allowed"},
+		{"12 digit INN of a legal entity", organizations.Organization{Type: organizations.LLC, INN: "500100732259"}, false, "invalid INN: a legal entity has 10 digits"},
+		{"10 digit INN of an entrepreneur", organizations.Organization{Type: organizations.IE, INN: "7707083893"}, false, "invalid INN: an individual entrepreneur has 12 digits"},
+		{"10 digit INN check digit", organizations.Organization{Type: organizations.LLC, INN: "7707083894"}, false, "invalid INN: checksum mismatch"},
+		{"12 digit INN first check digit", organizations.Organization{Type: organizations.IE, INN: "500100732269"}, false, "invalid INN: checksum mismatch"},
+		{"12 digit INN second check digit", organizations.Organization{Type: organizations.IE, INN: "500100732258"}, false, "invalid INN: checksum mismatch"},
+		{"OGRN starting with 3", organizations.Organization{Type: organizations.LLC, OGRN: "3027700132195"}, false, "invalid OGRN: a legal entity has 13 digits starting with 1 or 5"},
+		{"OGRNIP of a legal entity", organizations.Organization{Type: organizations.LLC, OGRN: "304500116000157"}, false, "invalid OGRN: a legal entity has 13 digits starting with 1 or 5"},
+		{"OGRN check digit", organizations.Organization{Type: organizations.LLC, OGRN: "1027700132196"}, false, "invalid OGRN: checksum mismatch"},
+		{"OGRNIP check digit", organizations.Organization{Type: organizations.IE, OGRN: "304500116000158"}, false, "invalid OGRN: checksum mismatch"},
+		{"KPP of an entrepreneur", organizations.Organization{Type: organizations.IE, KPP: "773601001"}, false, "invalid KPP: an individual entrepreneur has no KPP"},
+		{"KPP with small letters", organizations.Organization{Type: organizations.LLC, KPP: "7736ab001"}, false, "invalid KPP: expected 4 digits, 2 digits or capital letters and 3 digits"},
+		{"KPP with a letter out of place", organizations.Organization{Type: organizations.LLC, KPP: "7736010A1"}, false, "invalid KPP: expected 4 digits, 2 digits or capital letters and 3 digits"},
+		{"short KPP", organizations.Organization{Type: organizations.LLC, KPP: "77360100"}, false, "invalid KPP: expected 4 digits, 2 digits or capital letters and 3 digits"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ""
+			if err := organizations.ValidateIdentifiers(&tc.org, tc.required); err != nil {
+				got = err.Error()
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
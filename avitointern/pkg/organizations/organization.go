@@ -0,0 +1,34 @@
+package organizations
+
+type Type string
+
+const (
+	IE  Type = "IE"
+	LLC Type = "LLC"
+	JSC Type = "JSC"
+)
+
+const (
+	MaxNameLength        = 100
+	MaxDescriptionLength = 500
+)
+
+type Organization struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        Type   `json:"type"`
+	INN         string `json:"inn"`
+	OGRN        string `json:"ogrn"` // OGRNIP for an individual entrepreneur.
+	KPP         string `json:"kpp,omitempty"`
+	CreatedAt   string `json:"createdAt"` // RFC3339 format.
+	UpdatedAt   string `json:"updatedAt"` // RFC3339 format.
+}
+
+func ValidType(orgType string) bool {
+	switch Type(orgType) {
+	case IE, LLC, JSC:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Page sizes of the listings, as in the spec: limit is 0 to MaxLimit.
+const (
+	DefaultLimit = 5
+	MaxLimit     = 50
+)
+
+var ErrBadCursor = errors.New("invalid cursor")
+
+// Cursor points just past the last row of a page: the value of the sort key
+// and the ID that breaks ties. Clients get it as an opaque string.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func Decode(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	var c Cursor
+	if err = json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrBadCursor
+	}
+	return &c, nil
+}
+
+// Clamp bounds limit to 0..MaxLimit, for callers that did not validate it.
+func Clamp(limit int32) int32 {
+	return min(max(limit, 0), MaxLimit)
+}
+
+// Lookahead is the number of rows to fetch for a page of limit: one more, to
+// find out whether another page follows. The limit is clamped first, so the
+// extra row cannot overflow it.
+func Lookahead(limit int32) int32 {
+	return Clamp(limit) + 1
+}
+
+// Trim cuts the extra row a listing fetched with Lookahead and reports
+// whether another page follows.
+func Trim[T any](list []T, limit int32) ([]T, bool) {
+	limit = Clamp(limit)
+	if limit == 0 {
+		return list[:0], false
+	}
+	if len(list) <= int(limit) {
+		return list, false
+	}
+	return list[:limit], true
+}
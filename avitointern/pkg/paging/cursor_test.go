@@ -0,0 +1,97 @@
+package paging_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"avitointern/pkg/paging"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cases := []paging.Cursor{
+		{Sort: "name", Value: "Доставка", ID: "550e8400-e29b-41d4-a716-446655440000"},
+		{Sort: "createdAt", Desc: true, Value: "2006-01-02T15:04:05Z", ID: "t1"},
+		{Sort: "name", Value: "", ID: "t2"},
+	}
+
+	for _, c := range cases {
+		encoded := c.Encode()
+		decoded, err := paging.Decode(encoded)
+		if err != nil {
+			t.Fatalf("decode %q: %v", encoded, err)
+		}
+		if *decoded != c {
+			t.Errorf("got %+v, want %+v", *decoded, c)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	cases := map[string]string{
+		"empty":          "",
+		"not base64":     "not a cursor!",
+		"not JSON":       base64.RawURLEncoding.EncodeToString([]byte("cursor")),
+		"no ID":          base64.RawURLEncoding.EncodeToString([]byte(`{"s":"name","v":"x"}`)),
+		"wrong ID type":  base64.RawURLEncoding.EncodeToString([]byte(`{"s":"name","v":"x","id":1}`)),
+		"JSON not a map": base64.RawURLEncoding.EncodeToString([]byte(`["name","x","t1"]`)),
+	}
+
+	for name, s := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := paging.Decode(s); !errors.Is(err, paging.ErrBadCursor) {
+				t.Errorf("got %v, want ErrBadCursor", err)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	list := []int{1, 2, 3, 4}
+	cases := []struct {
+		name  string
+		list  []int
+		limit int32
+		want  []int
+		more  bool
+	}{
+		{"zero limit", list, 0, []int{}, false},
+		{"negative limit", list, -1, []int{}, false},
+		{"fewer rows than the limit", list[:2], 3, []int{1, 2}, false},
+		{"exactly the limit", list[:3], 3, []int{1, 2, 3}, false},
+		{"the lookahead row", list, 3, []int{1, 2, 3}, true},
+		{"empty list", nil, 3, nil, false},
+		{"limit above the maximum", make([]int, paging.MaxLimit+1), math.MaxInt32, make([]int, paging.MaxLimit), true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, more := paging.Trim(tc.list, tc.limit)
+			if len(got) != len(tc.want) || len(got) > 0 && !reflect.DeepEqual(got, tc.want) || more != tc.more {
+				t.Errorf("got %v, %v, want %v, %v", got, more, tc.want, tc.more)
+			}
+		})
+	}
+}
+
+func TestLookahead(t *testing.T) {
+	cases := []struct {
+		limit, want int32
+	}{
+		{0, 1},
+		{5, 6},
+		{paging.MaxLimit, paging.MaxLimit + 1},
+		{paging.MaxLimit + 1, paging.MaxLimit + 1},
+		{math.MaxInt32, paging.MaxLimit + 1},
+		{-1, 1},
+		{math.MinInt32, 1},
+	}
+
+	for _, tc := range cases {
+		if got := paging.Lookahead(tc.limit); got != tc.want {
+			t.Errorf("Lookahead(%d) = %d, want %d", tc.limit, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+
+	"avitointern/pkg/api"
+	"avitointern/pkg/apperr"
+	"avitointern/pkg/authz"
+	"avitointern/pkg/database"
+	"avitointern/pkg/handlers"
+	"avitointern/pkg/mail"
+	"avitointern/pkg/middleware"
+	"avitointern/pkg/openapi"
+	"avitointern/pkg/session"
+	"avitointern/pkg/tenders"
+	"avitointern/pkg/token"
+	"avitointern/pkg/user"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Config holds what the API needs to serve requests. Storage is chosen by
+// the caller, so the same routes run on Postgres or in memory.
+type Config struct {
+	DB       database.Database
+	Users    user.UserRepo
+	Sessions *session.SessionsManager
+	Tokens   *token.Manager
+	Mailer   mail.Mailer
+	// States is shared with the storage, which closes tenders on its own
+	// when a bid is approved.
+	States *tenders.StateMachine
+	Spec   *openapi.Spec
+	Debug  bool
+	// ExposeResetTokens returns password reset tokens in the response
+	// instead of mailing them only, for local development.
+	ExposeResetTokens bool
+	Tmpl              *template.Template
+	Logger            *zap.SugaredLogger
+}
+
+// Handler wires the API under /api and the HTML pages at the root.
+func Handler(cfg Config) http.Handler {
+	policy := authz.NewPolicy(cfg.Users, cfg.Logger)
+	if cfg.Mailer == nil {
+		cfg.Mailer = mail.NewStub(cfg.Logger)
+	}
+	if cfg.States == nil {
+		cfg.States = handlers.NewTenderStates(cfg.Users, cfg.Logger)
+	}
+
+	userHandler := &handlers.UserHandler{
+		Tmpl:     cfg.Tmpl,
+		UserRepo: cfg.Users,
+		Logger:   cfg.Logger,
+		Sessions: cfg.Sessions,
+		Tokens:   cfg.Tokens,
+		Mailer:   cfg.Mailer,
+
+		ExposeResetTokens: cfg.ExposeResetTokens,
+	}
+
+	healthHandler := &handlers.HealthHandler{
+		SQL:    cfg.DB,
+		Logger: cfg.Logger,
+	}
+
+	bidsHandler := &handlers.BidsHandler{
+		SQL:    cfg.DB,
+		Authz:  policy,
+		Logger: cfg.Logger,
+	}
+
+	organizationsHandler := &handlers.OrganizationsHandler{
+		SQL:    cfg.DB,
+		Users:  cfg.Users,
+		Authz:  policy,
+		Logger: cfg.Logger,
+	}
+
+	tendersHandler := &handlers.TendersHandler{
+		SQL:    cfg.DB,
+		Authz:  policy,
+		States: cfg.States,
+		Tmpl:   cfg.Tmpl,
+		Logger: cfg.Logger,
+	}
+
+	r := mux.NewRouter()
+	api.Mount(r, &api.Handlers{
+		Users:         userHandler,
+		Health:        healthHandler,
+		Tenders:       tendersHandler,
+		Bids:          bidsHandler,
+		Organizations: organizationsHandler,
+	}, func(next http.Handler) http.Handler {
+		return middleware.Auth(cfg.Sessions, cfg.Tokens, cfg.Users, next)
+	}, func(next http.Handler) http.Handler {
+		return middleware.Validate(cfg.Spec, cfg.Debug, cfg.Logger, next)
+	})
+
+	// HTML pages keep cookie sessions optional and answer with redirects,
+	// the login form posts here rather than to the API.
+	pages := r.NewRoute().Subrouter()
+	pages.Use(func(next http.Handler) http.Handler {
+		return middleware.Session(cfg.Sessions, next)
+	})
+	pages.HandleFunc("/", userHandler.Index).Methods("GET")
+	pages.HandleFunc("/login", userHandler.Login).Methods("POST")
+	pages.HandleFunc("/logout", userHandler.Logout).Methods("POST")
+
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apperr.Write(w, r, apperr.ErrNotFound)
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apperr.Write(w, r, apperr.ErrMethodNotAllowed)
+	})
+
+	return middleware.RequestID(middleware.Language(middleware.Panic(cfg.Logger, middleware.AccessLog(cfg.Logger, r))))
+}
@@ -2,20 +2,36 @@ package session
 
 import (
 	"avitointern/pkg/user"
+	"context"
+	"errors"
+	"net"
 	"net/http"
-	"sync"
 	"time"
 )
 
+const (
+	DefaultIdleTTL     = 24 * time.Hour
+	DefaultAbsoluteTTL = 30 * 24 * time.Hour
+	// touchInterval limits how often a request writes last_seen_at.
+	touchInterval = time.Minute
+	maxUserAgent  = 255
+)
+
 type SessionsManager struct {
-	data map[string]*Session
-	mu   *sync.RWMutex
+	Store Store
+	Users user.UserRepo
+	// IdleTTL ends sessions not used for that long, AbsoluteTTL ends them
+	// that long after login regardless of activity.
+	IdleTTL     time.Duration
+	AbsoluteTTL time.Duration
 }
 
-func NewSessionsManager() *SessionsManager {
+func NewSessionsManager(store Store, users user.UserRepo) *SessionsManager {
 	return &SessionsManager{
-		data: make(map[string]*Session, 10),
-		mu:   &sync.RWMutex{},
+		Store:       store,
+		Users:       users,
+		IdleTTL:     DefaultIdleTTL,
+		AbsoluteTTL: DefaultAbsoluteTTL,
 	}
 }
 
@@ -24,31 +40,67 @@ func (sm *SessionsManager) Check(r *http.Request) (*Session, error) {
 	if err == http.ErrNoCookie {
 		return nil, ErrNoAuth
 	}
-	userID := sessionCookie.Value
+	ctx := r.Context()
+
+	sess, err := sm.Store.Get(ctx, TokenID(sessionCookie.Value))
+	if err != nil {
+		return nil, err
+	}
 
-	sm.mu.RLock()
-	sess, ok := sm.data[userID]
-	sm.mu.RUnlock()
+	now := time.Now()
+	if sm.expired(sess, now) {
+		if err = sm.Store.Delete(ctx, sess.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrNoAuth
+	}
 
-	if !ok {
+	sess.User, err = sm.Users.GetUserByID(ctx, sess.UserID)
+	if errors.Is(err, user.ErrNoUser) {
 		return nil, ErrNoAuth
 	}
+	if err != nil {
+		return nil, err
+	}
 
+	if now.Sub(sess.LastSeenAt) > touchInterval {
+		sess.LastSeenAt = now
+		if err = sm.Store.Touch(ctx, sess.ID, now); err != nil {
+			return nil, err
+		}
+	}
 	return sess, nil
 }
 
-func (sm *SessionsManager) Create(w http.ResponseWriter, user *user.User) (*Session, error) {
-	sess := NewSession(user)
+func (sm *SessionsManager) expired(sess *Session, now time.Time) bool {
+	return now.Sub(sess.LastSeenAt) > sm.IdleTTL || now.Sub(sess.CreatedAt) > sm.AbsoluteTTL
+}
 
-	sm.mu.Lock()
-	sm.data[sess.UserID] = sess
-	sm.mu.Unlock()
+func (sm *SessionsManager) Create(w http.ResponseWriter, r *http.Request, user *user.User) (*Session, error) {
+	sess, token, err := NewSession(user)
+	if err != nil {
+		return nil, err
+	}
+	sess.UserAgent = r.UserAgent()
+	if len(sess.UserAgent) > maxUserAgent {
+		sess.UserAgent = sess.UserAgent[:maxUserAgent]
+	}
+	sess.RemoteAddr = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		sess.RemoteAddr = host
+	}
+
+	if err = sm.Store.Create(r.Context(), sess); err != nil {
+		return nil, err
+	}
 
 	cookie := &http.Cookie{
-		Name:    "session_id",
-		Value:   sess.ID,
-		Expires: time.Now().Add(90 * 24 * time.Hour),
-		Path:    "/",
+		Name:     "session_id",
+		Value:    token,
+		Expires:  sess.CreatedAt.Add(sm.AbsoluteTTL),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
 	}
 	http.SetCookie(w, cookie)
 	return sess, nil
@@ -60,9 +112,9 @@ func (sm *SessionsManager) DestroyCurrent(w http.ResponseWriter, r *http.Request
 		return err
 	}
 
-	sm.mu.Lock()
-	delete(sm.data, sess.UserID)
-	sm.mu.Unlock()
+	if err = sm.Store.Delete(r.Context(), sess.ID); err != nil {
+		return err
+	}
 
 	cookie := http.Cookie{
 		Name:    "session_id",
@@ -72,3 +124,43 @@ func (sm *SessionsManager) DestroyCurrent(w http.ResponseWriter, r *http.Request
 	http.SetCookie(w, &cookie)
 	return nil
 }
+
+// List returns the user's sessions that have not expired, newest first.
+func (sm *SessionsManager) List(ctx context.Context, userID string) ([]*Session, error) {
+	all, err := sm.Store.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]*Session, 0, len(all))
+	for _, sess := range all {
+		if !sm.expired(sess, now) {
+			active = append(active, sess)
+		}
+	}
+	return active, nil
+}
+
+// Revoke ends one session of the user; ErrNoAuth if the user has no such session.
+func (sm *SessionsManager) Revoke(ctx context.Context, userID, id string) error {
+	sess, err := sm.Store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sess.UserID != userID {
+		return ErrNoAuth
+	}
+	return sm.Store.Delete(ctx, id)
+}
+
+// RevokeAll ends every session of the user except keepID, if set.
+func (sm *SessionsManager) RevokeAll(ctx context.Context, userID, keepID string) (int64, error) {
+	return sm.Store.DeleteByUser(ctx, userID, keepID)
+}
+
+// Cleanup removes expired sessions from the store.
+func (sm *SessionsManager) Cleanup(ctx context.Context) (int64, error) {
+	now := time.Now()
+	return sm.Store.DeleteExpired(ctx, now.Add(-sm.IdleTTL), now.Add(-sm.AbsoluteTTL))
+}
@@ -0,0 +1,141 @@
+package session
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type refreshToken struct {
+	userID    string
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	data    map[string]*Session
+	refresh map[string]refreshToken
+	mu      *sync.RWMutex
+}
+
+var _ Store = &MemoryStore{}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data:    make(map[string]*Session, 10),
+		refresh: make(map[string]refreshToken),
+		mu:      &sync.RWMutex{},
+	}
+}
+
+func (st *MemoryStore) Create(_ context.Context, sess *Session) error {
+	sessCopy := *sess
+	sessCopy.User = nil
+
+	st.mu.Lock()
+	st.data[sess.ID] = &sessCopy
+	st.mu.Unlock()
+	return nil
+}
+
+func (st *MemoryStore) Get(_ context.Context, id string) (*Session, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	sess, ok := st.data[id]
+	if !ok {
+		return nil, ErrNoAuth
+	}
+	sessCopy := *sess
+	return &sessCopy, nil
+}
+
+func (st *MemoryStore) Touch(_ context.Context, id string, lastSeenAt time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if sess, ok := st.data[id]; ok {
+		sess.LastSeenAt = lastSeenAt
+	}
+	return nil
+}
+
+func (st *MemoryStore) ListByUser(_ context.Context, userID string) ([]*Session, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	list := make([]*Session, 0)
+	for _, sess := range st.data {
+		if sess.UserID == userID {
+			sessCopy := *sess
+			list = append(list, &sessCopy)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+func (st *MemoryStore) Delete(_ context.Context, id string) error {
+	st.mu.Lock()
+	delete(st.data, id)
+	st.mu.Unlock()
+	return nil
+}
+
+func (st *MemoryStore) DeleteByUser(_ context.Context, userID, keepID string) (int64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var deleted int64
+	for id, sess := range st.data {
+		if sess.UserID == userID && id != keepID {
+			delete(st.data, id)
+			deleted++
+		}
+	}
+	for id, token := range st.refresh {
+		if token.userID == userID {
+			delete(st.refresh, id)
+		}
+	}
+	return deleted, nil
+}
+
+func (st *MemoryStore) DeleteExpired(_ context.Context, idleBefore, createdBefore time.Time) (int64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var deleted int64
+	for id, sess := range st.data {
+		if sess.LastSeenAt.Before(idleBefore) || sess.CreatedAt.Before(createdBefore) {
+			delete(st.data, id)
+			deleted++
+		}
+	}
+	now := time.Now()
+	for id, token := range st.refresh {
+		if now.After(token.expiresAt) {
+			delete(st.refresh, id)
+		}
+	}
+	return deleted, nil
+}
+
+func (st *MemoryStore) SaveRefresh(_ context.Context, id, userID string, expiresAt time.Time) error {
+	st.mu.Lock()
+	st.refresh[id] = refreshToken{userID: userID, expiresAt: expiresAt}
+	st.mu.Unlock()
+	return nil
+}
+
+func (st *MemoryStore) ConsumeRefresh(_ context.Context, id string) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	token, ok := st.refresh[id]
+	if !ok {
+		return false, nil
+	}
+	delete(st.refresh, id)
+	return time.Now().Before(token.expiresAt), nil
+}
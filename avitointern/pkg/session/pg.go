@@ -0,0 +1,153 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgStore keeps sessions in the sessions table, so every replica sees them.
+type PgStore struct {
+	Pool *pgxpool.Pool
+}
+
+var _ Store = &PgStore{}
+
+func NewPgStore(pool *pgxpool.Pool) *PgStore {
+	return &PgStore{Pool: pool}
+}
+
+const sessionColumns = `id, user_id::text, created_at, last_seen_at, user_agent, remote_addr`
+
+// invalidTextRepresentation is the Postgres SQLSTATE for a value that does
+// not parse as the column type, here a user ID that is not a uuid.
+const invalidTextRepresentation = "22P02"
+
+func isInvalidUUID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row rowScanner) (*Session, error) {
+	var sess Session
+	err := row.Scan(&sess.ID, &sess.UserID, &sess.CreatedAt, &sess.LastSeenAt, &sess.UserAgent, &sess.RemoteAddr)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoAuth
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+func (st *PgStore) Create(ctx context.Context, sess *Session) error {
+	query := `INSERT INTO sessions (id, user_id, created_at, last_seen_at, user_agent, remote_addr)
+			  VALUES ($1, $2::uuid, $3, $4, $5, $6)`
+
+	_, err := st.Pool.Exec(ctx, query, sess.ID, sess.UserID, sess.CreatedAt, sess.LastSeenAt,
+		sess.UserAgent, sess.RemoteAddr)
+	return err
+}
+
+func (st *PgStore) Get(ctx context.Context, id string) (*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+	return scanSession(st.Pool.QueryRow(ctx, query, id))
+}
+
+func (st *PgStore) Touch(ctx context.Context, id string, lastSeenAt time.Time) error {
+	_, err := st.Pool.Exec(ctx, `UPDATE sessions SET last_seen_at = $1 WHERE id = $2`, lastSeenAt, id)
+	return err
+}
+
+func (st *PgStore) ListByUser(ctx context.Context, userID string) ([]*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE user_id = $1::uuid ORDER BY created_at DESC`
+
+	list := make([]*Session, 0)
+	rows, err := st.Pool.Query(ctx, query, userID)
+	if isInvalidUUID(err) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sess *Session
+		sess, err = scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, sess)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (st *PgStore) Delete(ctx context.Context, id string) error {
+	_, err := st.Pool.Exec(ctx, `DELETE FROM sessions WHERE id = $1`, id)
+	return err
+}
+
+func (st *PgStore) DeleteByUser(ctx context.Context, userID, keepID string) (int64, error) {
+	tx, err := st.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, `DELETE FROM sessions WHERE user_id = $1::uuid AND id <> $2`, userID, keepID)
+	if isInvalidUUID(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1::uuid`, userID); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (st *PgStore) DeleteExpired(ctx context.Context, idleBefore, createdBefore time.Time) (int64, error) {
+	tag, err := st.Pool.Exec(ctx, `DELETE FROM sessions WHERE last_seen_at < $1 OR created_at < $2`,
+		idleBefore, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = st.Pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (st *PgStore) SaveRefresh(ctx context.Context, id, userID string, expiresAt time.Time) error {
+	_, err := st.Pool.Exec(ctx, `INSERT INTO refresh_tokens (id, user_id, expires_at) VALUES ($1, $2::uuid, $3)`,
+		id, userID, expiresAt)
+	return err
+}
+
+// ConsumeRefresh deletes the row, so two replicas racing on the same token
+// cannot both succeed.
+func (st *PgStore) ConsumeRefresh(ctx context.Context, id string) (bool, error) {
+	tag, err := st.Pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE id = $1 AND expires_at > CURRENT_TIMESTAMP`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -4,29 +4,46 @@ import (
 	"avitointern/pkg/user"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"fmt"
-	"log"
+	"time"
 )
 
+// Session is an authenticated login. ID is the SHA-256 of the cookie token:
+// it is safe to show and to revoke by, and the store never sees the token.
 type Session struct {
-	ID     string
-	UserID string
-	User   *user.User
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	User       *user.User `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	UserAgent  string     `json:"userAgent"`
+	RemoteAddr string     `json:"remoteAddr"`
 }
 
-func NewSession(user *user.User) *Session {
-	randID := make([]byte, 16)
-	_, err := rand.Read(randID)
-	if err != nil {
-		log.Println("err in session/session.go with newsession")
+// NewSession returns the session and the token for its cookie.
+func NewSession(user *user.User) (*Session, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
 	}
+	token := hex.EncodeToString(raw)
 
+	now := time.Now()
 	return &Session{
-		ID:     fmt.Sprintf("%x", randID),
-		UserID: user.ID,
-		User:   user,
-	}
+		ID:         TokenID(token),
+		UserID:     user.ID,
+		User:       user,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}, token, nil
+}
+
+// TokenID maps a cookie token to the session ID.
+func TokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 var (
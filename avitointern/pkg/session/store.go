@@ -0,0 +1,26 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// Store persists sessions and the IDs of live refresh tokens, so that both
+// are revoked together. Get returns ErrNoAuth for unknown IDs; session expiry
+// is decided by SessionsManager, the store only keeps the timestamps.
+type Store interface {
+	Create(ctx context.Context, sess *Session) error
+	Get(ctx context.Context, id string) (*Session, error)
+	Touch(ctx context.Context, id string, lastSeenAt time.Time) error
+	ListByUser(ctx context.Context, userID string) ([]*Session, error)
+	Delete(ctx context.Context, id string) error
+	// DeleteByUser revokes every session of the user except keepID, if set,
+	// and all of the user's refresh tokens.
+	DeleteByUser(ctx context.Context, userID, keepID string) (int64, error)
+	// DeleteExpired drops sessions idle since before idleBefore or created
+	// before createdBefore, and refresh tokens past their expiry.
+	DeleteExpired(ctx context.Context, idleBefore, createdBefore time.Time) (int64, error)
+
+	SaveRefresh(ctx context.Context, id, userID string, expiresAt time.Time) error
+	ConsumeRefresh(ctx context.Context, id string) (bool, error)
+}
@@ -0,0 +1,29 @@
+package tenders
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Diff lists the fields that differ between two versions of a tender, in a
+// fixed order so that responses are stable.
+func Diff(from, to *TenderVer) []FieldChange {
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"name", from.TenderName, to.TenderName},
+		{"description", from.TenderDescription, to.TenderDescription},
+		{"serviceType", from.ServiceType, to.ServiceType},
+		{"status", string(from.Status), string(to.Status)},
+	}
+
+	changes := make([]FieldChange, 0, len(fields))
+	for _, f := range fields {
+		if f.from != f.to {
+			changes = append(changes, FieldChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+	return changes
+}
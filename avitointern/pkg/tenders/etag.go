@@ -0,0 +1,81 @@
+package tenders
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrBadETag = errors.New("malformed entity tag")
+
+// ETag is the strong entity tag of a tender revision.
+func ETag(version int32) string {
+	return `"` + strconv.FormatInt(int64(version), 10) + `"`
+}
+
+func ParseETag(etag string) (int32, error) {
+	etag = strings.TrimSpace(etag)
+	if len(etag) < 3 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, ErrBadETag
+	}
+	version, err := strconv.ParseInt(etag[1:len(etag)-1], 10, 32)
+	if err != nil || version < 1 {
+		return 0, ErrBadETag
+	}
+	return int32(version), nil
+}
+
+// IfMatch is a parsed If-Match header. The zero value is an absent header and
+// matches every revision.
+type IfMatch struct {
+	present  bool
+	any      bool
+	versions []int32
+}
+
+// ParseIfMatch reads "*" or a comma-separated list of entity tags. Weak tags
+// and strong tags that are not a tender revision are valid but never match.
+func ParseIfMatch(header string) (IfMatch, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return IfMatch{}, nil
+	}
+	if header == "*" {
+		return IfMatch{present: true, any: true}, nil
+	}
+
+	m := IfMatch{present: true}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		weak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' || strings.Contains(tag[1:len(tag)-1], `"`) {
+			return IfMatch{}, ErrBadETag
+		}
+		if weak {
+			continue
+		}
+		if version, err := ParseETag(tag); err == nil {
+			m.versions = append(m.versions, version)
+		}
+	}
+	return m, nil
+}
+
+// Present reports whether the header was sent.
+func (m IfMatch) Present() bool {
+	return m.present
+}
+
+// Matches reports whether a tender at version satisfies the header.
+func (m IfMatch) Matches(version int32) bool {
+	if !m.present || m.any {
+		return true
+	}
+	for _, v := range m.versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
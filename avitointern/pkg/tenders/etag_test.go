@@ -0,0 +1,56 @@
+package tenders_test
+
+import (
+	"errors"
+	"testing"
+
+	"avitointern/pkg/tenders"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	cases := []struct {
+		header  string
+		present bool
+		matches []int32
+		misses  []int32
+	}{
+		{``, false, []int32{1, 3}, nil},
+		{`*`, true, []int32{1, 3}, nil},
+		{`"3"`, true, []int32{3}, []int32{1, 4}},
+		{` "1", "3" `, true, []int32{1, 3}, []int32{2}},
+		{`W/"3"`, true, nil, []int32{3}},
+		{`W/"3", "4"`, true, []int32{4}, []int32{3}},
+		{`"abc"`, true, nil, []int32{1}},
+		{`""`, true, nil, []int32{1}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.header, func(t *testing.T) {
+			m, err := tenders.ParseIfMatch(tc.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Present() != tc.present {
+				t.Errorf("Present() = %v, want %v", m.Present(), tc.present)
+			}
+			for _, v := range tc.matches {
+				if !m.Matches(v) {
+					t.Errorf("version %d does not match", v)
+				}
+			}
+			for _, v := range tc.misses {
+				if m.Matches(v) {
+					t.Errorf("version %d matches", v)
+				}
+			}
+		})
+	}
+}
+
+func TestParseIfMatchInvalid(t *testing.T) {
+	for _, header := range []string{`3`, `"3`, `W/3`, `"3", *`, `"3",`, `"a"b"`} {
+		if _, err := tenders.ParseIfMatch(header); !errors.Is(err, tenders.ErrBadETag) {
+			t.Errorf("%q: got %v, want ErrBadETag", header, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package tenders
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"avitointern/pkg/paging"
+)
+
+type SortField string
+
+const (
+	SortName      SortField = "name"
+	SortCreatedAt SortField = "createdAt"
+	SortVersion   SortField = "version"
+)
+
+// Filter narrows down tender listings. Zero fields do not filter; the
+// created-at range includes CreatedFrom and excludes CreatedTo. Results are
+// ordered by Sort (name by default) and then by tender ID, so pages are stable;
+// After continues a listing past the tender its cursor was made from.
+type Filter struct {
+	ServiceTypes   []ServiceType
+	Statuses       []Status
+	OrganizationID string
+	Author         string
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	NamePrefix     string
+	Sort           SortField
+	Desc           bool
+	After          *paging.Cursor
+}
+
+func ValidServiceType(serviceType string) bool {
+	switch ServiceType(serviceType) {
+	case Construction, Delivery, Manufacture:
+		return true
+	}
+	return false
+}
+
+func ValidSort(field string) bool {
+	switch SortField(field) {
+	case SortName, SortCreatedAt, SortVersion:
+		return true
+	}
+	return false
+}
+
+// Match reports whether the tender passes the filter. It mirrors the SQL
+// conditions for in-memory storage.
+func (f *Filter) Match(tender *Tender) bool {
+	if len(f.ServiceTypes) > 0 && !containsServiceType(f.ServiceTypes, tender.ServiceType) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsStatus(f.Statuses, tender.Status) {
+		return false
+	}
+	if f.OrganizationID != "" && tender.OrganizationID != f.OrganizationID {
+		return false
+	}
+	if f.Author != "" && tender.Author != f.Author {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(tender.TenderName), strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+	createdAt := parseCreatedAt(tender)
+	if !f.CreatedFrom.IsZero() && createdAt.Before(f.CreatedFrom) {
+		return false
+	}
+	if !f.CreatedTo.IsZero() && !createdAt.Before(f.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+func parseCreatedAt(tender *Tender) time.Time {
+	createdAt, _ := time.Parse(time.RFC3339, tender.CreatedAt)
+	return createdAt
+}
+
+// SetCursor continues the listing after the cursor, which must have been made
+// for the same sort order.
+func (f *Filter) SetCursor(c *paging.Cursor) error {
+	if c.Sort != string(f.Sort) || c.Desc != f.Desc {
+		return paging.ErrBadCursor
+	}
+	if _, err := cursorTender(c); err != nil {
+		return err
+	}
+	f.After = c
+	return nil
+}
+
+// CursorAfter returns the cursor that continues the listing after tender.
+func (f *Filter) CursorAfter(tender *Tender) *paging.Cursor {
+	c := &paging.Cursor{Sort: string(f.Sort), Desc: f.Desc, ID: tender.TenderID}
+	switch f.Sort {
+	case SortCreatedAt:
+		c.Value = tender.CreatedAt
+	case SortVersion:
+		c.Value = strconv.FormatInt(int64(tender.Version), 10)
+	default:
+		c.Value = tender.TenderName
+	}
+	return c
+}
+
+// CursorValue returns the sort key of the cursor typed for the sort column.
+func (f *Filter) CursorValue() interface{} {
+	tender, _ := cursorTender(f.After)
+	switch f.Sort {
+	case SortCreatedAt:
+		return parseCreatedAt(tender)
+	case SortVersion:
+		return tender.Version
+	}
+	return tender.TenderName
+}
+
+// cursorTender builds a tender holding the cursor's sort key, for comparisons.
+func cursorTender(c *paging.Cursor) (*Tender, error) {
+	tender := &Tender{TenderID: c.ID}
+	switch SortField(c.Sort) {
+	case SortCreatedAt:
+		if _, err := time.Parse(time.RFC3339, c.Value); err != nil {
+			return nil, paging.ErrBadCursor
+		}
+		tender.CreatedAt = c.Value
+	case SortVersion:
+		version, err := strconv.ParseInt(c.Value, 10, 32)
+		if err != nil {
+			return nil, paging.ErrBadCursor
+		}
+		tender.Version = int32(version)
+	default:
+		tender.TenderName = c.Value
+	}
+	return tender, nil
+}
+
+// Page sorts list the way the filter's SQL ORDER BY does and drops the
+// tenders up to and including the cursor.
+func (f *Filter) Page(list []*Tender) []*Tender {
+	sort.SliceStable(list, func(i, j int) bool {
+		return f.before(list[i], list[j])
+	})
+	if f.After == nil {
+		return list
+	}
+	after, err := cursorTender(f.After)
+	if err != nil {
+		return list[:0]
+	}
+	for i, tender := range list {
+		if f.before(after, tender) {
+			return list[i:]
+		}
+	}
+	return list[:0]
+}
+
+// before reports whether a comes before b in the filter's order.
+func (f *Filter) before(a, b *Tender) bool {
+	if f.Desc {
+		a, b = b, a
+	}
+	switch f.Sort {
+	case SortCreatedAt:
+		at, bt := parseCreatedAt(a), parseCreatedAt(b)
+		if !at.Equal(bt) {
+			return at.Before(bt)
+		}
+	case SortVersion:
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+	default:
+		if a.TenderName != b.TenderName {
+			return a.TenderName < b.TenderName
+		}
+	}
+	return a.TenderID < b.TenderID
+}
+
+func containsServiceType(list []ServiceType, value ServiceType) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStatus(list []Status, value Status) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package tenders
+
+import (
+	"context"
+	"fmt"
+
+	"avitointern/pkg/apperr"
+)
+
+// transitions lists the allowed status changes. A closed tender is final.
+var transitions = map[Status][]Status{
+	Created:   {Published, Closed},
+	Published: {Closed},
+}
+
+func ValidStatus(status string) bool {
+	switch Status(status) {
+	case Created, Published, Closed:
+		return true
+	}
+	return false
+}
+
+func CanTransition(from, to Status) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrIllegalTransition = apperr.ErrIllegalTransition
+
+// TransitionError is returned for a transition that is not in the table or
+// that a guard rejected; it matches ErrIllegalTransition.
+type TransitionError struct {
+	From, To Status
+	Reason   string
+}
+
+func (e *TransitionError) Error() string {
+	format, args := e.Message()
+	return fmt.Sprintf(format, args...)
+}
+
+// Message lets the API translate the error; the guard's reason is a message
+// of its own.
+func (e *TransitionError) Message() (string, []interface{}) {
+	if e.Reason == "" {
+		return "cannot change tender status from %s to %s", []interface{}{e.From, e.To}
+	}
+	return "cannot change tender status from %s to %s: %s", []interface{}{e.From, e.To, apperr.Text(e.Reason)}
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrIllegalTransition
+}
+
+// Transition is a requested status change; Tender is the state before it.
+type Transition struct {
+	Tender *Tender
+	To     Status
+	Actor  string
+}
+
+// Guard vetoes a transition by returning an error, normally a *TransitionError.
+type Guard func(ctx context.Context, tr Transition) error
+
+// MemberOf reports whether the user may act for the organization.
+type MemberOf func(ctx context.Context, username, organizationID string) (bool, error)
+
+// OwnedBy is a guard that only lets members of the tender's organization make
+// the transition.
+func OwnedBy(member MemberOf) Guard {
+	return func(ctx context.Context, tr Transition) error {
+		ok, err := member(ctx, tr.Actor, tr.Tender.OrganizationID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &TransitionError{From: tr.Tender.Status, To: tr.To,
+				Reason: "only the owning organization may do this"}
+		}
+		return nil
+	}
+}
+
+// Hook runs after a transition was stored; updated is the new revision.
+type Hook func(ctx context.Context, tr Transition, updated *Tender)
+
+// StateMachine checks tender status changes against the transition table and
+// the guards registered for the target status, and runs hooks afterwards.
+type StateMachine struct {
+	guards map[Status][]Guard
+	hooks  []Hook
+}
+
+func NewStateMachine() *StateMachine {
+	return &StateMachine{guards: make(map[Status][]Guard)}
+}
+
+// Guard registers g for transitions into the status to.
+func (sm *StateMachine) Guard(to Status, g Guard) {
+	sm.guards[to] = append(sm.guards[to], g)
+}
+
+func (sm *StateMachine) OnTransition(h Hook) {
+	sm.hooks = append(sm.hooks, h)
+}
+
+func (sm *StateMachine) Check(ctx context.Context, tr Transition) error {
+	from := tr.Tender.Status
+	if !CanTransition(from, tr.To) {
+		return &TransitionError{From: from, To: tr.To}
+	}
+	for _, guard := range sm.guards[tr.To] {
+		if err := guard(ctx, tr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply checks the transition, stores it with apply and runs the hooks.
+// apply should only succeed on top of the revision the check was made on.
+func (sm *StateMachine) Apply(ctx context.Context, tr Transition, apply func() (*Tender, error)) (*Tender, error) {
+	if err := sm.Check(ctx, tr); err != nil {
+		return nil, err
+	}
+	updated, err := apply()
+	if err != nil {
+		return nil, err
+	}
+	sm.Notify(ctx, tr, updated)
+	return updated, nil
+}
+
+// Notify runs the hooks for a transition that was checked and stored without
+// Apply, as part of a larger change; call it once the change is committed.
+func (sm *StateMachine) Notify(ctx context.Context, tr Transition, updated *Tender) {
+	for _, hook := range sm.hooks {
+		hook(ctx, tr, updated)
+	}
+}
@@ -0,0 +1,140 @@
+package tenders_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"avitointern/pkg/tenders"
+)
+
+func transition(from, to tenders.Status) tenders.Transition {
+	return tenders.Transition{
+		Tender: &tenders.Tender{TenderID: "t1", Status: from, OrganizationID: "org", Version: 1},
+		To:     to,
+		Actor:  "george",
+	}
+}
+
+func TestStateMachineTransitions(t *testing.T) {
+	cases := []struct {
+		from, to tenders.Status
+		allowed  bool
+	}{
+		{tenders.Created, tenders.Published, true},
+		{tenders.Created, tenders.Closed, true},
+		{tenders.Published, tenders.Closed, true},
+		{tenders.Created, tenders.Created, false},
+		{tenders.Published, tenders.Created, false},
+		{tenders.Published, tenders.Published, false},
+		{tenders.Closed, tenders.Created, false},
+		{tenders.Closed, tenders.Published, false},
+		{tenders.Closed, tenders.Closed, false},
+	}
+
+	sm := tenders.NewStateMachine()
+	for _, tc := range cases {
+		t.Run(string(tc.from)+" to "+string(tc.to), func(t *testing.T) {
+			err := sm.Check(context.Background(), transition(tc.from, tc.to))
+			if tc.allowed {
+				if err != nil {
+					t.Errorf("got %v, want the transition allowed", err)
+				}
+				return
+			}
+			var trErr *tenders.TransitionError
+			if !errors.As(err, &trErr) || !errors.Is(err, tenders.ErrIllegalTransition) {
+				t.Fatalf("got %v, want a *TransitionError", err)
+			}
+			if trErr.From != tc.from || trErr.To != tc.to || trErr.Reason != "" {
+				t.Errorf("got %+v, want %s to %s without a reason", trErr, tc.from, tc.to)
+			}
+		})
+	}
+}
+
+func TestStateMachineGuard(t *testing.T) {
+	members := map[string]bool{"george": true}
+	lookupErr := errors.New("lookup failed")
+	sm := tenders.NewStateMachine()
+	sm.Guard(tenders.Published, tenders.OwnedBy(func(_ context.Context, username, organizationID string) (bool, error) {
+		if username == "broken" {
+			return false, lookupErr
+		}
+		return organizationID == "org" && members[username], nil
+	}))
+
+	ctx := context.Background()
+	tr := transition(tenders.Created, tenders.Published)
+	if err := sm.Check(ctx, tr); err != nil {
+		t.Errorf("member: got %v, want the transition allowed", err)
+	}
+
+	tr.Actor = "stranger"
+	err := sm.Check(ctx, tr)
+	var trErr *tenders.TransitionError
+	if !errors.As(err, &trErr) || trErr.Reason == "" {
+		t.Errorf("stranger: got %v, want a *TransitionError with a reason", err)
+	}
+
+	tr.Actor = "broken"
+	if err = sm.Check(ctx, tr); !errors.Is(err, lookupErr) {
+		t.Errorf("broken lookup: got %v, want the lookup error", err)
+	}
+
+	// The guard is registered for Published only.
+	tr = transition(tenders.Created, tenders.Closed)
+	tr.Actor = "stranger"
+	if err = sm.Check(ctx, tr); err != nil {
+		t.Errorf("close: got %v, want the transition allowed", err)
+	}
+}
+
+func TestStateMachineHooks(t *testing.T) {
+	var calls []string
+	sm := tenders.NewStateMachine()
+	sm.Guard(tenders.Published, func(context.Context, tenders.Transition) error {
+		calls = append(calls, "guard")
+		return nil
+	})
+	sm.OnTransition(func(_ context.Context, _ tenders.Transition, updated *tenders.Tender) {
+		calls = append(calls, "first hook "+string(updated.Status))
+	})
+	sm.OnTransition(func(context.Context, tenders.Transition, *tenders.Tender) {
+		calls = append(calls, "second hook")
+	})
+
+	ctx := context.Background()
+	tr := transition(tenders.Created, tenders.Published)
+	updated, err := sm.Apply(ctx, tr, func() (*tenders.Tender, error) {
+		calls = append(calls, "apply")
+		return &tenders.Tender{TenderID: "t1", Status: tenders.Published, Version: 2}, nil
+	})
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("got %v, %v, want the updated tender", updated, err)
+	}
+	want := []string{"guard", "apply", "first hook Published", "second hook"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+
+	// Neither an illegal transition nor a failed store runs the hooks.
+	calls = nil
+	storeErr := errors.New("store failed")
+	if _, err = sm.Apply(ctx, transition(tenders.Closed, tenders.Published), func() (*tenders.Tender, error) {
+		calls = append(calls, "apply")
+		return nil, nil
+	}); !errors.Is(err, tenders.ErrIllegalTransition) {
+		t.Errorf("illegal: got %v, want ErrIllegalTransition", err)
+	}
+	if _, err = sm.Apply(ctx, tr, func() (*tenders.Tender, error) {
+		return nil, storeErr
+	}); !errors.Is(err, storeErr) {
+		t.Errorf("store: got %v, want the store error", err)
+	}
+	want = []string{"guard"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+}
@@ -33,8 +33,11 @@ type TenderVer struct {
 	TenderName        string `json:"name"`
 	TenderDescription string `json:"description"`
 	ServiceType       string `json:"serviceType"`
-	Version           int32  `json:"Version"`
+	Version           int32  `json:"version"`
 	Status            Status `json:"status"`
+	ModifiedBy        string `json:"modifiedBy"`
+	ModifiedAt        string `json:"modifiedAt"` // RFC3339 format.
+	RollbackOf        int32  `json:"rollbackOf,omitempty"`
 }
 
 type TendersRepo interface {
@@ -0,0 +1,9 @@
+package tenders
+
+// Viewer is the user tenders are read for. Published tenders are visible to
+// everyone; Created and Closed ones only to the responsibles and viewers of
+// the owning organization and to platform admins.
+type Viewer struct {
+	Username string
+	Admin    bool
+}
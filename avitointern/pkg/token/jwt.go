@@ -0,0 +1,210 @@
+package token
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Type string
+
+const (
+	Access  Type = "access"
+	Refresh Type = "refresh"
+)
+
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+	// leeway tolerates clock skew between the services exchanging tokens.
+	leeway = 30 * time.Second
+)
+
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken = errors.New("token has expired")
+	ErrWrongType    = errors.New("wrong token type")
+	ErrReusedToken  = errors.New("refresh token has already been used or was revoked")
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+type Claims struct {
+	Subject   string `json:"sub"`
+	Username  string `json:"name"`
+	Type      Type   `json:"typ"`
+	ID        string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type Pair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"` // seconds until the access token expires.
+}
+
+// RefreshStore keeps the IDs of refresh tokens that may still be used. It is
+// shared by all replicas and survives restarts, and revoking a user's
+// sessions drops their refresh tokens from it.
+type RefreshStore interface {
+	SaveRefresh(ctx context.Context, id, userID string, expiresAt time.Time) error
+	// ConsumeRefresh removes the token and reports whether it was there and
+	// not expired.
+	ConsumeRefresh(ctx context.Context, id string) (bool, error)
+}
+
+// Manager issues and verifies HS256 JWTs. Refresh tokens are single use and
+// revocable: a token works only while its ID is in the store.
+type Manager struct {
+	Keys       *Keyset
+	Store      RefreshStore
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+func NewManager(keys *Keyset, store RefreshStore) *Manager {
+	return &Manager{
+		Keys:       keys,
+		Store:      store,
+		AccessTTL:  DefaultAccessTTL,
+		RefreshTTL: DefaultRefreshTTL,
+	}
+}
+
+// Issue returns a new access and refresh token for the user.
+func (m *Manager) Issue(ctx context.Context, userID, username string) (*Pair, error) {
+	now := time.Now()
+	access, err := m.sign(&Claims{Subject: userID, Username: username, Type: Access}, now, m.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{Subject: userID, Username: username, Type: Refresh}
+	refresh, err := m.sign(claims, now, m.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Store.SaveRefresh(ctx, claims.ID, userID, time.Unix(claims.ExpiresAt, 0).Add(leeway)); err != nil {
+		return nil, err
+	}
+	return &Pair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(m.AccessTTL / time.Second),
+	}, nil
+}
+
+// Refresh consumes a refresh token and returns its claims; the caller issues
+// the new pair, so that it can check the user still exists.
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (*Claims, error) {
+	claims, err := m.Verify(refreshToken, Refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := m.Store.ConsumeRefresh(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrReusedToken
+	}
+	return claims, nil
+}
+
+// sign fills in the ID and the timestamps of claims and returns the token.
+func (m *Manager) sign(claims *Claims, now time.Time, ttl time.Duration) (string, error) {
+	kid, secret, err := m.Keys.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	jti := make([]byte, 16)
+	if _, err = rand.Read(jti); err != nil {
+		return "", err
+	}
+	claims.ID = hex.EncodeToString(jti)
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+
+	headerJSON, err := json.Marshal(header{Alg: "HS256", Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encode(headerJSON) + "." + encode(claimsJSON)
+	return signingInput + "." + encode(mac(secret, signingInput)), nil
+}
+
+// Verify checks the signature, expiry and type of the token.
+func (m *Manager) Verify(tokenString string, want Type) (*Claims, error) {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeJSON(parts[0], &h); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if h.Alg != "HS256" {
+		return nil, fmt.Errorf("%w: alg %q", ErrInvalidToken, h.Alg)
+	}
+	secret, err := m.Keys.key(h.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac(secret, parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err = decodeJSON(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().After(time.Unix(claims.ExpiresAt, 0).Add(leeway)) {
+		return nil, ErrExpiredToken
+	}
+	if claims.Type != want {
+		return nil, ErrWrongType
+	}
+	return &claims, nil
+}
+
+func mac(secret []byte, signingInput string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(signingInput))
+	return h.Sum(nil)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeJSON(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,117 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const minSecretLength = 32
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Keyset holds the HMAC secrets by key ID. Tokens are signed with the current
+// key and verified with whichever key their kid header names, so a key can be
+// rotated by adding a new current key and keeping the old one until the
+// tokens it signed have expired.
+type Keyset struct {
+	mu      *sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+func NewKeyset() *Keyset {
+	return &Keyset{
+		mu:   &sync.RWMutex{},
+		keys: make(map[string][]byte),
+	}
+}
+
+// Add registers a key; the first key added becomes the current one.
+func (ks *Keyset) Add(kid string, secret []byte) error {
+	if kid == "" {
+		return errors.New("key id is empty")
+	}
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("key %q: secret must be at least %d bytes", kid, minSecretLength)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys[kid] = append([]byte(nil), secret...)
+	if ks.current == "" {
+		ks.current = kid
+	}
+	return nil
+}
+
+// Rotate makes kid the signing key. The previous key stays valid for verification.
+func (ks *Keyset) Rotate(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, ok := ks.keys[kid]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	ks.current = kid
+	return nil
+}
+
+func (ks *Keyset) signingKey() (string, []byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if ks.current == "" {
+		return "", nil, ErrUnknownKey
+	}
+	return ks.current, ks.keys[ks.current], nil
+}
+
+func (ks *Keyset) key(kid string) ([]byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	secret, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	return secret, nil
+}
+
+// KeysetFromEnv reads JWT_KEYS ("kid1:secret1,kid2:secret2") and JWT_CURRENT_KEY.
+// Without JWT_KEYS a random key is generated, so tokens do not survive a restart.
+func KeysetFromEnv() (*Keyset, bool, error) {
+	ks := NewKeyset()
+
+	raw := strings.TrimSpace(os.Getenv("JWT_KEYS"))
+	if raw == "" {
+		secret := make([]byte, minSecretLength)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, false, err
+		}
+		err := ks.Add("ephemeral-"+hex.EncodeToString(secret[:4]), secret)
+		return ks, false, err
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, false, fmt.Errorf("JWT_KEYS: %q is not kid:secret", pair)
+		}
+		if err := ks.Add(kid, []byte(secret)); err != nil {
+			return nil, false, fmt.Errorf("JWT_KEYS: %w", err)
+		}
+	}
+
+	if current := os.Getenv("JWT_CURRENT_KEY"); current != "" {
+		if err := ks.Rotate(current); err != nil {
+			return nil, false, fmt.Errorf("JWT_CURRENT_KEY: %w", err)
+		}
+	}
+	return ks, true, nil
+}
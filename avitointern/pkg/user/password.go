@@ -0,0 +1,74 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"avitointern/pkg/apperr"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 8
+	// MaxPasswordLength is the bcrypt input limit in bytes.
+	MaxPasswordLength = 72
+	ResetTokenTTL     = 30 * time.Minute
+)
+
+var (
+	ErrWeakPassword = apperr.New(apperr.CodeWeakPassword, http.StatusBadRequest, "password must be 8 to 72 bytes long")
+	ErrBadToken     = apperr.New(apperr.CodeBadResetToken, http.StatusBadRequest, "reset token is invalid or expired")
+	ErrUserExists   = apperr.New(apperr.CodeUserExists, http.StatusConflict, "user already exists")
+)
+
+// dummyHash is compared against when the user does not exist, so that a
+// missing user takes as long to reject as a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+func ValidPassword(pass string) error {
+	if len(pass) < MinPasswordLength || len(pass) > MaxPasswordLength {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+func HashPassword(pass string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword returns ErrBadPass unless pass matches hash. An empty hash
+// never matches: such users cannot log in until a password is set.
+func CheckPassword(hash, pass string) error {
+	if hash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(pass))
+		return ErrBadPass
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)); err != nil {
+		return ErrBadPass
+	}
+	return nil
+}
+
+// NewResetToken returns a random token for the user and the hash that is
+// stored instead of it.
+func NewResetToken() (token, tokenHash string, err error) {
+	raw := make([]byte, 32)
+	if _, err = rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(raw)
+	return token, HashResetToken(token), nil
+}
+
+func HashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
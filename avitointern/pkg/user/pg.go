@@ -0,0 +1,286 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserPgRepository reads users from the employee table. OrganizationID is the
+// first organization the employee is responsible for, if any.
+type UserPgRepository struct {
+	Pool *pgxpool.Pool
+}
+
+var _ UserRepo = &UserPgRepository{}
+
+func NewPgRepo(pool *pgxpool.Pool) *UserPgRepository {
+	return &UserPgRepository{Pool: pool}
+}
+
+const userColumns = `e.id::text, e.username, COALESCE(e.first_name, ''), COALESCE(e.last_name, ''), e.password_hash,
+	COALESCE((SELECT r.organization_id::text FROM organization_responsible r
+		WHERE r.user_id = e.id ORDER BY r.id LIMIT 1), ''), e.is_admin, e.language`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Username, &u.FirstName, &u.LastName, &u.PasswordHash, &u.OrganizationID, &u.IsAdmin, &u.Language)
+	if errors.Is(err, pgx.ErrNoRows) || isInvalidUUID(err) {
+		return nil, ErrNoUser
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (repo *UserPgRepository) Authorize(ctx context.Context, username, pass string) (*User, error) {
+	u, err := repo.GetUserByUsername(ctx, username)
+	if errors.Is(err, ErrNoUser) {
+		_ = CheckPassword("", pass)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = CheckPassword(u.PasswordHash, pass); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (repo *UserPgRepository) GetUserByID(ctx context.Context, userID string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM employee e WHERE e.id = $1::uuid`
+	return scanUser(repo.Pool.QueryRow(ctx, query, userID))
+}
+
+func (repo *UserPgRepository) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM employee e WHERE e.username = $1`
+	return scanUser(repo.Pool.QueryRow(ctx, query, username))
+}
+
+func (repo *UserPgRepository) IsResponsible(ctx context.Context, username, organizationID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM organization_responsible r JOIN employee e ON e.id = r.user_id
+			  WHERE e.username = $1 AND r.organization_id = $2::uuid)`
+
+	var exists bool
+	err := repo.Pool.QueryRow(ctx, query, username, organizationID).Scan(&exists)
+	if isInvalidUUID(err) {
+		return false, nil
+	}
+	return exists, err
+}
+
+func (repo *UserPgRepository) ListResponsibles(ctx context.Context, organizationID string) ([]*User, error) {
+	query := `SELECT ` + userColumns + ` FROM employee e
+			  JOIN organization_responsible r ON r.user_id = e.id
+			  WHERE r.organization_id = $1::uuid
+			  ORDER BY e.username`
+
+	users := make([]*User, 0)
+	rows, err := repo.Pool.Query(ctx, query, organizationID)
+	if isInvalidUUID(err) {
+		return users, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u *User
+		u, err = scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (repo *UserPgRepository) IsViewer(ctx context.Context, username, organizationID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM organization_viewer v JOIN employee e ON e.id = v.user_id
+			  WHERE e.username = $1 AND v.organization_id = $2::uuid)`
+
+	var exists bool
+	err := repo.Pool.QueryRow(ctx, query, username, organizationID).Scan(&exists)
+	if isInvalidUUID(err) {
+		return false, nil
+	}
+	return exists, err
+}
+
+// Postgres SQLSTATE codes.
+const (
+	uniqueViolation           = "23505"
+	foreignKeyViolation       = "23503"
+	invalidTextRepresentation = "22P02"
+)
+
+// isInvalidUUID reports that Postgres could not cast an ID to uuid. Such an
+// ID names no row, so callers answer as for a missing one.
+func isInvalidUUID(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == invalidTextRepresentation
+}
+
+func (repo *UserPgRepository) AddResponsible(ctx context.Context, organizationID, userID string) error {
+	query := `INSERT INTO organization_responsible (organization_id, user_id) VALUES ($1::uuid, $2::uuid)
+			  ON CONFLICT (organization_id, user_id) DO NOTHING`
+
+	_, err := repo.Pool.Exec(ctx, query, organizationID, userID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation || isInvalidUUID(err) {
+		return ErrNoUser
+	}
+	return err
+}
+
+// RemoveResponsible locks the organization row, so that two removals at once
+// cannot both see another responsible left and remove the last two.
+func (repo *UserPgRepository) RemoveResponsible(ctx context.Context, organizationID, userID string) error {
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `SELECT 1 FROM organization WHERE id = $1::uuid FOR UPDATE`
+	_, err = tx.Exec(ctx, query, organizationID)
+	if isInvalidUUID(err) {
+		return ErrNotResponsible
+	}
+	if err != nil {
+		return err
+	}
+
+	var responsible bool
+	var count int
+	query = `SELECT COALESCE(bool_or(user_id = $2::uuid), false), count(*) FROM organization_responsible
+			  WHERE organization_id = $1::uuid`
+	err = tx.QueryRow(ctx, query, organizationID, userID).Scan(&responsible, &count)
+	if isInvalidUUID(err) {
+		return ErrNotResponsible
+	}
+	if err != nil {
+		return err
+	}
+	if !responsible {
+		return ErrNotResponsible
+	}
+	if count == 1 {
+		return ErrLastResponsible
+	}
+
+	query = `DELETE FROM organization_responsible WHERE organization_id = $1::uuid AND user_id = $2::uuid`
+	if _, err = tx.Exec(ctx, query, organizationID, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (repo *UserPgRepository) Create(ctx context.Context, u *User) error {
+	query := `INSERT INTO employee (username, first_name, last_name, password_hash)
+			  VALUES ($1, $2, $3, $4) RETURNING id::text`
+
+	err := repo.Pool.QueryRow(ctx, query, u.Username, u.FirstName, u.LastName, u.PasswordHash).Scan(&u.ID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrUserExists
+	}
+	return err
+}
+
+func (repo *UserPgRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	query := `UPDATE employee SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2::uuid`
+
+	tag, err := repo.Pool.Exec(ctx, query, passwordHash, userID)
+	if isInvalidUUID(err) {
+		return ErrNoUser
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNoUser
+	}
+	return nil
+}
+
+func (repo *UserPgRepository) SetLanguage(ctx context.Context, userID, lang string) error {
+	query := `UPDATE employee SET language = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2::uuid`
+
+	tag, err := repo.Pool.Exec(ctx, query, lang, userID)
+	if isInvalidUUID(err) {
+		return ErrNoUser
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNoUser
+	}
+	return nil
+}
+
+func (repo *UserPgRepository) SaveResetToken(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO password_reset_tokens (token_hash, user_id, expires_at) VALUES ($1, $2::uuid, $3)`
+
+	_, err := repo.Pool.Exec(ctx, query, tokenHash, userID, expiresAt)
+	return err
+}
+
+func (repo *UserPgRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (*User, error) {
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var userID string
+	query := `UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP
+			  WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+			  RETURNING user_id::text`
+	err = tx.QueryRow(ctx, query, tokenHash).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBadToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE employee SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2::uuid`
+	if _, err = tx.Exec(ctx, query, passwordHash, userID); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT ` + userColumns + ` FROM employee e WHERE e.id = $1::uuid`
+	u, err := scanUser(tx.QueryRow(ctx, query, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
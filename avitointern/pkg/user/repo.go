@@ -1,23 +1,51 @@
 package user
 
 import (
-	"errors"
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"avitointern/pkg/apperr"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrNoUser  = errors.New("no user found")
-	ErrBadPass = errors.New("invald password")
+	ErrNoUser  = apperr.New(apperr.CodeUserNotFound, http.StatusNotFound, "no user found")
+	ErrBadPass = apperr.New(apperr.CodeBadCredentials, http.StatusUnauthorized, "invald password")
+	// ErrBadCredentials is what a login answers for both an unknown user and
+	// a wrong password, so the answer does not reveal which users exist.
+	ErrBadCredentials = apperr.New(apperr.CodeBadCredentials, http.StatusUnauthorized, "invalid credentials")
+	ErrNotResponsible = apperr.New(apperr.CodeNotResponsible, http.StatusNotFound, "the user is not responsible for the organization")
+	// ErrLastResponsible keeps an organization from being left with no one
+	// to manage it.
+	ErrLastResponsible = apperr.New(apperr.CodeLastResponsible, http.StatusConflict, "cannot remove the last responsible")
 )
 
 var _ UserRepo = &UserMemoryRepository{}
 
+type resetToken struct {
+	userID    string
+	expiresAt time.Time
+}
+
 type UserMemoryRepository struct {
-	data map[string]*User
+	data         map[string]*User
+	tokens       map[string]resetToken
+	responsibles map[string]map[string]bool // organization ID -> usernames
+	viewers      map[string]map[string]bool // organization ID -> usernames
+	mu           *sync.RWMutex
 }
 
 func NewMemoryRepo() *UserMemoryRepository {
+	hash, err := HashPassword("qwer")
+	if err != nil {
+		log.Println("err in user/repo.go with HashPassword")
+	}
+	const organizationID = "123e4567-e89b-12d3-a456-426614174000"
 	return &UserMemoryRepository{
 		data: map[string]*User{
 			"george": &User{
@@ -25,28 +53,40 @@ func NewMemoryRepo() *UserMemoryRepository {
 				Username:       "george",
 				FirstName:      "George",
 				LastName:       "Original",
-				Password:       "qwer",
-				OrganizationID: "123e4567-e89b-12d3-a456-426614174000",
+				PasswordHash:   hash,
+				OrganizationID: organizationID,
 			},
 		},
+		tokens: make(map[string]resetToken),
+		responsibles: map[string]map[string]bool{
+			organizationID: {"george": true},
+		},
+		viewers: make(map[string]map[string]bool),
+		mu:      &sync.RWMutex{},
 	}
 }
 
-func (repo *UserMemoryRepository) Authorize(username, pass string) (*User, error) {
+func (repo *UserMemoryRepository) Authorize(_ context.Context, username, pass string) (*User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
 	u, ok := repo.data[username]
 	if !ok {
+		_ = CheckPassword("", pass)
 		return nil, ErrNoUser
 	}
 
-	// piu pau authorization
-	if u.Password != pass {
-		return nil, ErrBadPass
+	if err := CheckPassword(u.PasswordHash, pass); err != nil {
+		return nil, err
 	}
 
 	return u, nil
 }
 
-func (repo *UserMemoryRepository) GetUserByID(userID string) (*User, error) {
+func (repo *UserMemoryRepository) GetUserByID(_ context.Context, userID string) (*User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
 	for _, user := range repo.data {
 		if user.ID == userID {
 			return user, nil
@@ -54,3 +94,175 @@ func (repo *UserMemoryRepository) GetUserByID(userID string) (*User, error) {
 	}
 	return nil, ErrNoUser
 }
+
+func (repo *UserMemoryRepository) GetUserByUsername(_ context.Context, username string) (*User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	u, ok := repo.data[username]
+	if !ok {
+		return nil, ErrNoUser
+	}
+	return u, nil
+}
+
+func (repo *UserMemoryRepository) IsResponsible(_ context.Context, username, organizationID string) (bool, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.responsibles[organizationID][username], nil
+}
+
+func (repo *UserMemoryRepository) ListResponsibles(_ context.Context, organizationID string) ([]*User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	users := make([]*User, 0)
+	for username := range repo.responsibles[organizationID] {
+		users = append(users, repo.data[username])
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (repo *UserMemoryRepository) IsViewer(_ context.Context, username, organizationID string) (bool, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.viewers[organizationID][username], nil
+}
+
+// AddResponsible is idempotent. The user's OrganizationID is set to the
+// organization when they had none, as in the Postgres repository.
+func (repo *UserMemoryRepository) AddResponsible(_ context.Context, organizationID, userID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	u := repo.userByID(userID)
+	if u == nil {
+		return ErrNoUser
+	}
+	if repo.responsibles[organizationID] == nil {
+		repo.responsibles[organizationID] = make(map[string]bool)
+	}
+	repo.responsibles[organizationID][u.Username] = true
+
+	if u.OrganizationID == "" {
+		updated := *u
+		updated.OrganizationID = organizationID
+		repo.data[u.Username] = &updated
+	}
+	return nil
+}
+
+func (repo *UserMemoryRepository) RemoveResponsible(_ context.Context, organizationID, userID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	u := repo.userByID(userID)
+	if u == nil {
+		return ErrNoUser
+	}
+	if !repo.responsibles[organizationID][u.Username] {
+		return ErrNotResponsible
+	}
+	if len(repo.responsibles[organizationID]) == 1 {
+		return ErrLastResponsible
+	}
+	delete(repo.responsibles[organizationID], u.Username)
+
+	if u.OrganizationID == organizationID {
+		updated := *u
+		updated.OrganizationID = ""
+		for orgID, usernames := range repo.responsibles {
+			if usernames[u.Username] {
+				updated.OrganizationID = orgID
+				break
+			}
+		}
+		repo.data[u.Username] = &updated
+	}
+	return nil
+}
+
+func (repo *UserMemoryRepository) userByID(userID string) *User {
+	for _, u := range repo.data {
+		if u.ID == userID {
+			return u
+		}
+	}
+	return nil
+}
+
+func (repo *UserMemoryRepository) Create(_ context.Context, u *User) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.data[u.Username]; ok {
+		return ErrUserExists
+	}
+	if u.ID == "" {
+		u.ID = uuid.New().String()
+	}
+	repo.data[u.Username] = u
+	return nil
+}
+
+func (repo *UserMemoryRepository) UpdatePassword(_ context.Context, userID, passwordHash string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for username, u := range repo.data {
+		if u.ID == userID {
+			updated := *u
+			updated.PasswordHash = passwordHash
+			repo.data[username] = &updated
+			return nil
+		}
+	}
+	return ErrNoUser
+}
+
+func (repo *UserMemoryRepository) SetLanguage(_ context.Context, userID, lang string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for username, u := range repo.data {
+		if u.ID == userID {
+			updated := *u
+			updated.Language = lang
+			repo.data[username] = &updated
+			return nil
+		}
+	}
+	return ErrNoUser
+}
+
+func (repo *UserMemoryRepository) SaveResetToken(_ context.Context, userID, tokenHash string, expiresAt time.Time) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.tokens[tokenHash] = resetToken{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (repo *UserMemoryRepository) ResetPassword(_ context.Context, tokenHash, passwordHash string) (*User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	token, ok := repo.tokens[tokenHash]
+	if !ok || time.Now().After(token.expiresAt) {
+		return nil, ErrBadToken
+	}
+	delete(repo.tokens, tokenHash)
+
+	for username, u := range repo.data {
+		if u.ID == token.userID {
+			updated := *u
+			updated.PasswordHash = passwordHash
+			repo.data[username] = &updated
+			return &updated, nil
+		}
+	}
+	return nil, ErrBadToken
+}
@@ -1,15 +1,39 @@
 package user
 
+import (
+	"context"
+	"time"
+)
+
 type User struct {
 	ID             string
 	Username       string
 	FirstName      string
 	LastName       string
-	Password       string
+	PasswordHash   string
 	OrganizationID string
+	IsAdmin        bool
+	// Language is the user's choice for messages, empty to follow
+	// Accept-Language.
+	Language string
 }
 
 type UserRepo interface {
-	Authorize(username, pass string) (*User, error)
-	GetUserByID(userID string) (*User, error)
+	Authorize(ctx context.Context, username, pass string) (*User, error)
+	GetUserByID(ctx context.Context, userID string) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	IsResponsible(ctx context.Context, username, organizationID string) (bool, error)
+	ListResponsibles(ctx context.Context, organizationID string) ([]*User, error)
+	IsViewer(ctx context.Context, username, organizationID string) (bool, error)
+	AddResponsible(ctx context.Context, organizationID, userID string) error
+	// RemoveResponsible fails with ErrLastResponsible instead of removing
+	// the only responsible; the check and the removal are atomic.
+	RemoveResponsible(ctx context.Context, organizationID, userID string) error
+
+	Create(ctx context.Context, u *User) error
+	UpdatePassword(ctx context.Context, userID, passwordHash string) error
+	SetLanguage(ctx context.Context, userID, lang string) error
+	SaveResetToken(ctx context.Context, userID, tokenHash string, expiresAt time.Time) error
+	// ResetPassword consumes an unexpired token and sets the new password hash.
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (*User, error)
 }